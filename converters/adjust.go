@@ -0,0 +1,140 @@
+package converters
+
+import (
+	"fmt"
+	"github.com/dan-lind/t6converter/model"
+	"github.com/pkg/errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A CorporateAction is a split and/or cash dividend with its ex-date.
+type CorporateAction struct {
+	Symbol   string
+	Date     time.Time
+	Split    float64 // new shares per old share, 2 for a 2-for-1 split, 0 if none
+	Dividend float64 // cash dividend per share, 0 if none
+}
+
+// ReadCorporateActions reads a csv file of symbol,date,split,dividend rows,
+// with an optional header line, and returns the actions by upper case symbol.
+// Dates are formatted 20060102 and splits are given as a ratio like 2, 2:1 or 3/2.
+func ReadCorporateActions(path string) (map[string][]CorporateAction, error) {
+	records, err := FileToCsv(path)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make(map[string][]CorporateAction)
+	for i, record := range records {
+		if i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "symbol") {
+			// skip header line
+			continue
+		}
+		if len(record) < 4 {
+			return nil, errors.Errorf("Expected 4 fields in corporate action %v, got %v", i, len(record))
+		}
+
+		var action CorporateAction
+		action.Symbol = strings.ToUpper(strings.TrimSpace(record[0]))
+		if action.Date, err = time.Parse("20060102", strings.TrimSpace(record[1])); err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("Failed to parse date of corporate action %v", i))
+		}
+		if action.Split, err = parseRatio(strings.TrimSpace(record[2])); err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("Failed to parse split of corporate action %v", i))
+		}
+		if dividend := strings.TrimSpace(record[3]); dividend != "" {
+			if action.Dividend, err = strconv.ParseFloat(dividend, 64); err != nil {
+				return nil, errors.WithMessage(err, fmt.Sprintf("Failed to parse dividend of corporate action %v", i))
+			}
+		}
+
+		actions[action.Symbol] = append(actions[action.Symbol], action)
+	}
+
+	return actions, nil
+}
+
+func parseRatio(ratio string) (float64, error) {
+	if ratio == "" {
+		return 0, nil
+	}
+	for _, sep := range []string{":", "/"} {
+		if parts := strings.SplitN(ratio, sep, 2); len(parts) == 2 {
+			num, err := strconv.ParseFloat(parts[0], 64)
+			if err != nil {
+				return 0, err
+			}
+			den, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil || den == 0 {
+				return 0, errors.Errorf("Invalid ratio %v", ratio)
+			}
+			return num / den, nil
+		}
+	}
+	return strconv.ParseFloat(ratio, 64)
+}
+
+// Adjust back-adjusts all bars before the ex-date of each action in place.
+// Splits divide prices by the ratio and multiply volume by it, dividends scale
+// prices by 1 - dividend / close of the last bar before the ex-date. The spread
+// in Val is scaled like the prices.
+func Adjust(recordMap map[int][]model.ZorroT6, actions []CorporateAction) {
+	type adjustment struct {
+		date   float64
+		price  float64
+		volume float64
+	}
+
+	adjustments := make([]adjustment, 0, len(actions))
+	for _, action := range actions {
+		a := adjustment{date: ConvertToOle(action.Date), price: 1, volume: 1}
+		if action.Split > 0 {
+			a.price /= action.Split
+			a.volume *= action.Split
+		}
+		if action.Dividend > 0 {
+			if close, ok := lastCloseBefore(recordMap, a.date); ok && close > action.Dividend {
+				a.price *= 1 - action.Dividend/close
+			}
+		}
+		adjustments = append(adjustments, a)
+	}
+
+	for _, records := range recordMap {
+		for i := range records {
+			price, volume := 1., 1.
+			for _, a := range adjustments {
+				if records[i].Date < a.date {
+					price *= a.price
+					volume *= a.volume
+				}
+			}
+			if price == 1 && volume == 1 {
+				continue
+			}
+			records[i].Open = float32(float64(records[i].Open) * price)
+			records[i].High = float32(float64(records[i].High) * price)
+			records[i].Low = float32(float64(records[i].Low) * price)
+			records[i].Close = float32(float64(records[i].Close) * price)
+			records[i].Val = float32(float64(records[i].Val) * price)
+			records[i].Vol = int32(math.Min(math.Round(float64(records[i].Vol)*volume), math.MaxInt32))
+		}
+	}
+}
+
+func lastCloseBefore(recordMap map[int][]model.ZorroT6, date float64) (float64, bool) {
+	var last model.ZorroT6
+	found := false
+	for _, records := range recordMap {
+		for _, record := range records {
+			if record.Date < date && (!found || record.Date > last.Date) {
+				last = record
+				found = true
+			}
+		}
+	}
+	return float64(last.Close), found
+}
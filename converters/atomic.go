@@ -0,0 +1,67 @@
+package converters
+
+import (
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// writeFileAtomic writes a file by calling write with a temporary file in the
+// same directory, which is synced and renamed to name once write succeeded.
+// A crash or interrupt therefore leaves either the old file or the complete
+// new one, never a truncated file that Zorro would load. Missing directories
+// are created. The file is not buffered, write buffers its own writes if it
+// makes many small ones.
+func writeFileAtomic(name string, write func(w io.Writer) error) (err error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(0644); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return errors.WithMessage(err, "Unable to sync file")
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), name); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(name))
+}
+
+// syncDir syncs the directory at path, so that a rename in it survives a
+// crash. Windows can't sync directories and doesn't need to, NTFS journals
+// the rename.
+func syncDir(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	if err := dir.Sync(); err != nil {
+		return errors.WithMessage(err, "Unable to sync directory")
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package converters
+
+import (
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// InputSuffixes are the file name endings of the csv inputs FileToCsv and
+// FileToStruct can read, compressed files are decompressed on the fly.
+var InputSuffixes = []string{"txt", "csv", "txt.gz", "csv.gz", "zip", "bz2"}
+
+// ReadInputs calls read with the decompressed contents of the file at path,
+// once for every csv and txt file of a zip archive, see eachInput.
+func ReadInputs(path string, read func(r io.Reader) error) error {
+	return eachInput(path, read)
+}
+
+// eachInput calls read with the decompressed contents of the file at path. For
+// zip archives read is called for every csv and txt file in it, in name order.
+// A txt file next to a csv file of the same name is skipped, HistData ships
+// such a status report with every csv file.
+func eachInput(path string, read func(r io.Reader) error) error {
+	lower := strings.ToLower(path)
+
+	if strings.HasSuffix(lower, ".zip") {
+		archive, err := zip.OpenReader(path)
+		if err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("Unable to read file with path %v", path))
+		}
+		defer archive.Close()
+
+		csvs := make(map[string]bool)
+		for _, file := range archive.File {
+			if name := strings.ToLower(file.Name); strings.HasSuffix(name, ".csv") {
+				csvs[strings.TrimSuffix(name, ".csv")] = true
+			}
+		}
+
+		files := make([]*zip.File, 0, len(archive.File))
+		for _, file := range archive.File {
+			name := strings.ToLower(file.Name)
+			if file.FileInfo().IsDir() {
+				continue
+			}
+			if strings.HasSuffix(name, ".csv") || strings.HasSuffix(name, ".txt") && !csvs[strings.TrimSuffix(name, ".txt")] {
+				files = append(files, file)
+			}
+		}
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].Name < files[j].Name
+		})
+
+		for _, file := range files {
+			r, err := file.Open()
+			if err != nil {
+				return errors.WithMessage(err, fmt.Sprintf("Unable to read %v in file %v", file.Name, path))
+			}
+			err = read(r)
+			r.Close()
+			if err != nil {
+				return errors.WithMessage(err, fmt.Sprintf("Unable to read %v in file %v", file.Name, path))
+			}
+		}
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("Unable to read file with path %v", path))
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	switch {
+	case strings.HasSuffix(lower, ".gz"):
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("Unable to decompress file %v", path))
+		}
+		defer gz.Close()
+		r = gz
+	case strings.HasSuffix(lower, ".bz2"):
+		r = bzip2.NewReader(file)
+	}
+
+	if err := read(r); err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("Unable to read records in file %v", path))
+	}
+	return nil
+}
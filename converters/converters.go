@@ -8,10 +8,12 @@ import (
 	"fmt"
 	"github.com/dan-lind/t6converter/model"
 	"github.com/pkg/errors"
+	"io"
 	"io/ioutil"
-	"log"
+	"math"
 	"os"
 	"path"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -19,148 +21,292 @@ import (
 )
 
 func Rw1minToStruct(records [][]string) (map[int][]model.ZorroT6, error) {
-	//Need to add check for missing data
+	return Parser{}.Rw1minToStruct(records)
+}
+
+// Rw1minToStruct converts intraday records, grouped by year. The parser's
+// profile defaults to Pitrading1min.
+func (p Parser) Rw1minToStruct(records [][]string) (map[int][]model.ZorroT6, error) {
+	p.Profile = p.profile(Pitrading1min)
+	return p.newBarReader(&sliceSource{records: records}, false).ReadAll()
+}
 
-	//t6records := make([]ZorroT6,0, len(records))
-	var t6records = make(map[int][]model.ZorroT6)
-	for i, record := range records {
+func RecordToStruct(record []string) (model.ZorroT6, time.Time, error) {
+	return Parser{}.RecordToStruct(record)
+}
 
-		t6, parsedTime, err := RecordToStruct(record)
+// RecordToStruct converts a single record laid out as the parser's profile,
+// which defaults to Pitrading1min.
+func (p Parser) RecordToStruct(record []string) (model.ZorroT6, time.Time, error) {
+	profile := p.profile(Pitrading1min)
+	if p.Zones.In == nil {
+		loc, err := profile.location()
 		if err != nil {
-			return nil, errors.WithMessage(err, fmt.Sprintf("Failed to parse time for record %v", i, ))
+			return model.ZorroT6{}, time.Time{}, err
 		}
-
-		t6records[parsedTime.Year()] = append(t6records[parsedTime.Year()], t6)
+		p.Zones.In = loc
 	}
 
-	return t6records, nil
-
-}
-
-func RecordToStruct(record []string) (model.ZorroT6, time.Time, error) {
 	var t6 model.ZorroT6
-	parsedTime, err := time.Parse("2006010215:04", record[0]+record[1])
+	parsedTime, err := p.Zones.Parse(profile.DateLayout, field(record, profile.Date)+field(record, profile.Time))
 	if err != nil {
 		return model.ZorroT6{}, time.Time{}, err
 	}
 
 	t6.Date = ConvertToOle(parsedTime)
-	if open, err := strconv.ParseFloat(record[2],32); err == nil {
+	if open, err := strconv.ParseFloat(field(record, profile.Open), 32); err == nil {
 		t6.Open = float32(open)
 	}
-	if high, err := strconv.ParseFloat(record[3],32); err == nil {
+	if high, err := strconv.ParseFloat(field(record, profile.High), 32); err == nil {
 		t6.High = float32(high)
 	}
-	if low, err := strconv.ParseFloat(record[4],32); err == nil {
+	if low, err := strconv.ParseFloat(field(record, profile.Low), 32); err == nil {
 		t6.Low = float32(low)
 	}
-	if close, err := strconv.ParseFloat(record[5],32); err == nil {
+	if close, err := strconv.ParseFloat(field(record, profile.Close), 32); err == nil {
 		t6.Close = float32(close)
 	}
-	if vol, err := strconv.ParseInt(record[6], 10,32); err == nil {
+	if vol, err := strconv.ParseFloat(field(record, profile.Volume), 64); err == nil {
 		t6.Vol = int32(vol)
 	}
+	if spread, err := strconv.ParseFloat(field(record, profile.Spread), 32); err == nil {
+		t6.Val = float32(spread)
+	} else if spread, ok := averageSpread(record, profile, t6); ok {
+		t6.Val = spread
+	}
 
 	return t6, parsedTime, nil
 }
 
+// averageSpread returns the average difference between the ask prices of t6
+// and the bid prices of record, false if the profile has no bid columns.
+func averageSpread(record []string, profile Profile, t6 model.ZorroT6) (float32, bool) {
+	var sum float64
+	var count int
+	for _, side := range []struct {
+		column int
+		ask    float32
+	}{
+		{profile.BidOpen, t6.Open},
+		{profile.BidHigh, t6.High},
+		{profile.BidLow, t6.Low},
+		{profile.BidClose, t6.Close},
+	} {
+		if bid, err := strconv.ParseFloat(field(record, side.column), 32); err == nil {
+			sum += float64(side.ask) - bid
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return float32(sum / float64(count)), true
+}
+
 func RwDailyToStruct(records [][]string) (map[int][]model.ZorroT6, error) {
-	//Need to add check for missing data
+	return Parser{}.RwDailyToStruct(records)
+}
 
-	var t6records []model.ZorroT6
-	for i, record := range records {
-		if i == 0 {
-			// skip header line
-			continue
-		}
-		var t6 model.ZorroT6
-		parsedTime, err := time.Parse("20060102", record[0])
-		if err != nil {
-			return nil, errors.WithMessage(err, fmt.Sprintf("Failed to parse time for record %v", i, ))
-		}
+// RwDailyToStruct converts daily records into a single group with key 0. The
+// parser's profile defaults to PitradingDaily.
+func (p Parser) RwDailyToStruct(records [][]string) (map[int][]model.ZorroT6, error) {
+	p.Profile = p.profile(PitradingDaily)
+	return p.newBarReader(&sliceSource{records: records}, true).ReadAll()
+}
 
-		t6.Date = ConvertToOle(parsedTime)
-		if open, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 32); err == nil {
-			t6.Open = float32(open)
-		}
-		if high, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 32); err == nil {
-			t6.High = float32(high)
+func FileToCsv(path string) ([][]string, error) {
+	return ReadCsv(path, ',')
+}
+
+// ReadCsv reads all records of the file at path using comma as field delimiter.
+// Compressed files are read as described for InputSuffixes, the csv files of a
+// zip archive are concatenated, dropping their header if it repeats the first.
+func ReadCsv(path string, comma rune) ([][]string, error) {
+	var records [][]string
+	err := eachInput(path, func(data io.Reader) error {
+		r := csv.NewReader(bufio.NewReader(data))
+		r.Comma = comma
+
+		entry, err := r.ReadAll()
+		if err != nil {
+			return err
 		}
-		if low, err := strconv.ParseFloat(strings.TrimSpace(record[3]), 32); err == nil {
-			t6.Low = float32(low)
+		if len(records) > 0 && len(entry) > 0 && equalRecords(records[0], entry[0]) {
+			entry = entry[1:]
 		}
-		if close, err := strconv.ParseFloat(strings.TrimSpace(record[4]), 32); err == nil {
-			t6.Close = float32(close)
+		records = append(records, entry...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func equalRecords(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
 		}
-		if len(record) == 6 {
-			if vol, err := strconv.ParseInt(record[5], 10,32); err == nil {
-				t6.Vol = int32(vol)
+	}
+	return true
+}
+
+func StructToT6File(recordMap map[int][]model.ZorroT6, outputPath string, inputPath string, daily bool) error {
+	return MergeStructToT6File(recordMap, outputPath, inputPath, daily, Overwrite)
+}
+
+// MergeStructToT6File writes the records like StructToT6File, but unless policy
+// is Overwrite it first merges them with the bars of any existing file.
+func MergeStructToT6File(recordMap map[int][]model.ZorroT6, outputPath string, inputPath string, daily bool, policy MergePolicy) error {
+	return WriteT6Files(recordMap, func(year int) string {
+		return T6FileName(outputPath, inputPath, year, daily)
+	}, policy)
+}
+
+// WriteT6Files writes the records of each year to the file called name(year),
+// creating its directory if needed. Years sharing a name are written to the
+// same file. Unless policy is Overwrite the records are first merged with the
+// bars of any existing file.
+func WriteT6Files(recordMap map[int][]model.ZorroT6, name func(year int) string, policy MergePolicy) error {
+	files := make(map[string][]model.ZorroT6)
+	for year, records := range recordMap {
+		files[name(year)] = append(files[name(year)], records...)
+	}
+
+	for name, records := range files {
+		if policy != Overwrite {
+			existing, err := T6FileToStruct(name)
+			if err == nil {
+				records = Merge(existing, records, policy)
+			} else if !os.IsNotExist(errors.Cause(err)) {
+				return err
 			}
-		} else {
-			t6.Vol = 0
 		}
 
-		t6.Val = float32(parsedTime.Year())
-		t6records = append(t6records, t6)
+		sort.Slice(records, func(i, j int) bool {
+			return records[i].Date > records[j].Date
+		})
+
+		if err := writeT6File(name, records); err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("Unable to write file with path %v", name))
+		}
 	}
+	return nil
+}
 
-	return map[int][]model.ZorroT6{0: t6records}, nil
+func writeT6File(name string, records []model.ZorroT6) error {
+	return writeFileAtomic(name, func(file io.Writer) error {
+		w := NewT6Writer(file)
+		if err := w.Write(records); err != nil {
+			return err
+		}
+		return w.Flush()
+	})
 }
 
-func FileToCsv(path string) ([][]string, error) {
+// T6FileName returns the name of the t6 file for the given year, or for all
+// years when daily.
+func T6FileName(outputPath string, inputPath string, year int, daily bool) string {
+	if daily {
+		return strings.Join([]string{outputPath, path.Base(inputPath), ".t6"}, "")
+	}
+	return strings.Join([]string{outputPath, path.Base(inputPath), "_", strconv.Itoa(year), ".t6"}, "")
+}
 
-	//data, err := ioutil.ReadFile(path)
-	//if err != nil {
-	//	return nil, errors.WithMessage(err, fmt.Sprintf("Unable to read file with path %v", path))
-	//}
-	data, err := os.Open(path) // For read access.
+func T6FileToStruct(path string) ([]model.ZorroT6, error) {
+	var records []model.ZorroT6
+	err := readAllRecords(path, binary.Size(model.ZorroT6{}), "T6", func(n int) interface{} {
+		records = make([]model.ZorroT6, n)
+		return records
+	})
+	return records, err
+}
+
+// readAllRecords decodes the file at path as records of size bytes into the
+// slice alloc returns for their number, the counterpart of writeAllRecords.
+func readAllRecords(path string, size int, format string, alloc func(n int) interface{}) error {
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		return nil, errors.WithMessage(err, fmt.Sprintf("Unable to read file with path %v", path))
+		return errors.WithMessage(err, fmt.Sprintf("Unable to read file with path %v", path))
 	}
-	r := csv.NewReader(bufio.NewReader(data))
 
-	records, err := r.ReadAll()
-	if err != nil {
-		return nil, errors.WithMessage(err, fmt.Sprintf("Unable to read records in file %v", path))
+	if len(data)%size != 0 {
+		return errors.Errorf("File %v is not a %v file, size %v is not a multiple of %v", path, format, len(data), size)
 	}
 
-	return records, nil
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, alloc(len(data)/size)); err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("Unable to decode records in file %v", path))
+	}
+	return nil
 }
 
-func StructToT6File(recordMap map[int][]model.ZorroT6, outputPath string, inputPath string, daily bool) {
-	buf := new(bytes.Buffer)
-
-	for _, t6records := range recordMap {
-		sort.Slice(t6records, func(i, j int) bool {
-			return t6records[i].Date > t6records[j].Date
-		})
+// StructToCsvFile writes the records oldest first in the Pitrading 1-minute
+// layout. The times are those of the records, the close of the bar, so the csv
+// is read back with a profile stamping bars at their close.
+func StructToCsvFile(records []model.ZorroT6, outputPath string) error {
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("Unable to create file with path %v", outputPath))
+	}
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("Unable to create file with path %v", outputPath))
 	}
+	defer file.Close()
 
-	if daily {
-		records := recordMap[0]
-		writeAllRecords(records, buf)
-		ioutil.WriteFile(strings.Join([]string{outputPath, path.Base(inputPath), ".t6"}, ""), buf.Bytes(), 0644)
-	} else {
-		for year, records := range recordMap {
-			buf.Reset()
-			writeAllRecords(records, buf)
-			ioutil.WriteFile(strings.Join([]string{outputPath, path.Base(inputPath), "_", strconv.Itoa(year), ".t6"}, ""), buf.Bytes(), 0644)
+	// T6 files are stored newest first, write the csv oldest first like the source data
+	sorted := make([]model.ZorroT6, len(records))
+	copy(sorted, records)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Date < sorted[j].Date
+	})
+
+	buf := bufio.NewWriter(file)
+	w := csv.NewWriter(buf)
+	for _, t6 := range sorted {
+		parsedTime := ConvertFromOle(t6.Date)
+		err := w.Write([]string{
+			parsedTime.Format("20060102"),
+			parsedTime.Format("15:04"),
+			strconv.FormatFloat(float64(t6.Open), 'f', -1, 32),
+			strconv.FormatFloat(float64(t6.High), 'f', -1, 32),
+			strconv.FormatFloat(float64(t6.Low), 'f', -1, 32),
+			strconv.FormatFloat(float64(t6.Close), 'f', -1, 32),
+			strconv.FormatInt(int64(t6.Vol), 10),
+		})
+		if err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("Unable to write records to file %v", outputPath))
 		}
 	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("Unable to write records to file %v", outputPath))
+	}
+	if err := buf.Flush(); err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("Unable to write records to file %v", outputPath))
+	}
+
+	return file.Close()
 }
 
-func writeAllRecords(records []model.ZorroT6, buf *bytes.Buffer) {
-	err := binary.Write(buf, binary.LittleEndian, records)
-	if err != nil {
-		log.Println("binary.Write failed:", err)
+func writeAllRecords(records interface{}, w io.Writer) error {
+	if err := binary.Write(w, binary.LittleEndian, records); err != nil {
+		return errors.WithMessage(err, "binary.Write failed")
 	}
+	return nil
 }
 
 func ConvertToOle(oledate time.Time) float64 {
-	return float64(oledate.Unix())/(24.*60.*60.) + 25569. // 25569. = DATE(1.1.1970 00:00)
+	seconds := float64(oledate.Unix()) + float64(oledate.Nanosecond())/1e9 // keep tick timestamps sub-second
+	return seconds/(24.*60.*60.) + 25569.                                  // 25569. = DATE(1.1.1970 00:00)
 }
 
 func ConvertFromOle(oledate float64) time.Time {
-	return time.Unix(int64((oledate-25569.)*24.*60.*60.), 0).UTC(); // 25569. = DATE(1.1.1970 00:00)
+	// Round to the nearest millisecond, truncating the float would turn 09:30 into 09:29:59
+	millis := math.Round((oledate - 25569.) * 24. * 60. * 60. * 1000.) // 25569. = DATE(1.1.1970 00:00)
+	return time.Unix(0, int64(millis)*int64(time.Millisecond)).UTC()
 }
-
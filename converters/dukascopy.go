@@ -0,0 +1,234 @@
+package converters
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"github.com/dan-lind/t6converter/model"
+	"github.com/pkg/errors"
+	"github.com/ulikunitz/xz/lzma"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bi5RecordSize is the size of a decompressed Dukascopy tick.
+const Bi5RecordSize = 20
+
+// A DukascopyTick is a tick of a Dukascopy bi5 file. Volumes are in millions.
+type DukascopyTick struct {
+	Time      time.Time
+	Ask       float32
+	Bid       float32
+	AskVolume float32
+	BidVolume float32
+}
+
+// PointSize returns the price unit of symbol in Dukascopy's files, which store
+// prices as integer points: a thousandth for yen pairs and metals, otherwise a
+// hundred thousandth.
+func PointSize(symbol string) float64 {
+	symbol = strings.ToUpper(symbol)
+	for _, s := range []string{"JPY", "XAU", "XAG"} {
+		if strings.Contains(symbol, s) {
+			return 1e-3
+		}
+	}
+	return 1e-5
+}
+
+// Bi5Hour returns the hour covered by the bi5 file at path. Dukascopy lays its
+// files out as SYMBOL/YYYY/MM/DD/HHh_ticks.bi5, counting months from 00.
+func Bi5Hour(path string) (time.Time, error) {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	if len(parts) < 4 {
+		return time.Time{}, errors.Errorf("Path %v is not laid out as YYYY/MM/DD/HHh_ticks.bi5", path)
+	}
+	parts = parts[len(parts)-4:]
+
+	var fields [4]int
+	for i, part := range parts {
+		if i == 3 {
+			part = strings.SplitN(part, "h", 2)[0]
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return time.Time{}, errors.Errorf("Path %v is not laid out as YYYY/MM/DD/HHh_ticks.bi5", path)
+		}
+		fields[i] = n
+	}
+	return time.Date(fields[0], time.Month(fields[1]+1), fields[2], fields[3], 0, 0, 0, time.UTC), nil
+}
+
+// Bi5Symbol returns the symbol of the bi5 file at path, the name of the
+// directory above its year.
+func Bi5Symbol(path string) string {
+	return filepath.Base(filepath.Dir(filepath.Dir(filepath.Dir(filepath.Dir(path)))))
+}
+
+// ReadBi5 decodes the LZMA compressed ticks of the hour starting at hour. Each
+// tick holds its millisecond offset into the hour, the ask and bid in points
+// and the ask and bid volume, all big-endian.
+func ReadBi5(r io.Reader, hour time.Time, point float64) ([]DukascopyTick, error) {
+	br := bufio.NewReader(r)
+	if _, err := br.Peek(1); err == io.EOF {
+		// Dukascopy stores hours without ticks as empty files
+		return nil, nil
+	}
+
+	lr, err := lzma.NewReader(br)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Unable to decompress bi5 data")
+	}
+	data, err := ioutil.ReadAll(lr)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Unable to decompress bi5 data")
+	}
+	if len(data)%Bi5RecordSize != 0 {
+		return nil, errors.Errorf("Decompressed size %v is not a multiple of %v", len(data), Bi5RecordSize)
+	}
+
+	ticks := make([]DukascopyTick, 0, len(data)/Bi5RecordSize)
+	for b := data; len(b) > 0; b = b[Bi5RecordSize:] {
+		offset := time.Duration(binary.BigEndian.Uint32(b[0:])) * time.Millisecond
+		ticks = append(ticks, DukascopyTick{
+			Time:      hour.Add(offset),
+			Ask:       float32(float64(binary.BigEndian.Uint32(b[4:])) * point),
+			Bid:       float32(float64(binary.BigEndian.Uint32(b[8:])) * point),
+			AskVolume: math.Float32frombits(binary.BigEndian.Uint32(b[12:])),
+			BidVolume: math.Float32frombits(binary.BigEndian.Uint32(b[16:])),
+		})
+	}
+	return ticks, nil
+}
+
+// Bi5FileToTicks reads the bi5 file at path, taking its hour and point size
+// from the path.
+func Bi5FileToTicks(path string) ([]DukascopyTick, error) {
+	hour, err := Bi5Hour(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("Unable to read file with path %v", path))
+	}
+	defer file.Close()
+
+	ticks, err := ReadBi5(file, hour, PointSize(Bi5Symbol(path)))
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("Unable to read file with path %v", path))
+	}
+	return ticks, nil
+}
+
+// TicksToT1 converts ticks to T1 ask quotes grouped by year. With bids, each
+// tick is followed by its bid quote, which Zorro marks by a negative price and
+// takes the spread from.
+func TicksToT1(ticks []DukascopyTick, bids bool) map[int][]model.ZorroT1 {
+	var t1records = make(map[int][]model.ZorroT1)
+	for _, tick := range ticks {
+		year := tick.Time.Year()
+		date := ConvertToOle(tick.Time)
+		t1records[year] = append(t1records[year], model.ZorroT1{Date: date, Price: tick.Ask})
+		if bids {
+			t1records[year] = append(t1records[year], model.ZorroT1{Date: date, Price: -tick.Bid})
+		}
+	}
+	return t1records
+}
+
+// TicksToT6 aggregates ticks into ask bars of the given period grouped by year,
+// see Resample. Bars are stamped with their close time, the ticks of 13:00 to
+// 13:01 make up the bar of 13:01, and a bar closing on new year's midnight
+// belongs to the new year. Vol is the number of ticks and Val the average
+// spread.
+func TicksToT6(ticks []DukascopyTick, period time.Duration) map[int][]model.ZorroT6 {
+	sorted := make([]DukascopyTick, len(ticks))
+	copy(sorted, ticks)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	bars := NewTickBars(period)
+	for _, tick := range sorted {
+		bars.Add(tick)
+	}
+	return bars.Bars()
+}
+
+// TickBars builds the bars of TicksToT6 while ticks are added, holding only the
+// finished bars and the one being built instead of all ticks.
+type TickBars struct {
+	period time.Duration
+	start  time.Time
+	bar    model.ZorroT6
+	spread float64
+	bars   map[int][]model.ZorroT6
+}
+
+func NewTickBars(period time.Duration) *TickBars {
+	return &TickBars{period: period, bars: make(map[int][]model.ZorroT6)}
+}
+
+// Add adds tick to its bar. Ticks must be added in time order.
+func (b *TickBars) Add(tick DukascopyTick) {
+	start := tick.Time.Truncate(b.period)
+	if b.bar.Vol == 0 || !start.Equal(b.start) {
+		b.flush()
+		b.start = start
+		b.bar = model.ZorroT6{Date: ConvertToOle(start.Add(b.period)), Open: tick.Ask, High: tick.Ask, Low: tick.Ask}
+		b.spread = 0
+	}
+
+	if tick.Ask > b.bar.High {
+		b.bar.High = tick.Ask
+	}
+	if tick.Ask < b.bar.Low {
+		b.bar.Low = tick.Ask
+	}
+	b.bar.Close = tick.Ask
+	b.bar.Vol++
+	b.spread += float64(tick.Ask - tick.Bid)
+}
+
+// Bars finishes the bar being built and returns all bars grouped by the year
+// of their close time, oldest first.
+func (b *TickBars) Bars() map[int][]model.ZorroT6 {
+	b.flush()
+	return b.bars
+}
+
+// Finished returns and removes the bars of the years before the one the bar
+// being built closes in, which no later tick changes.
+func (b *TickBars) Finished() map[int][]model.ZorroT6 {
+	finished := make(map[int][]model.ZorroT6)
+	if b.bar.Vol == 0 {
+		return finished
+	}
+	open := b.start.Add(b.period).Year()
+	for year, bars := range b.bars {
+		if year < open {
+			finished[year] = bars
+			delete(b.bars, year)
+		}
+	}
+	return finished
+}
+
+func (b *TickBars) flush() {
+	if b.bar.Vol == 0 {
+		return
+	}
+	b.bar.Val = float32(b.spread / float64(b.bar.Vol))
+	year := b.start.Add(b.period).Year()
+	b.bars[year] = append(b.bars[year], b.bar)
+	b.bar = model.ZorroT6{}
+}
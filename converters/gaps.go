@@ -0,0 +1,195 @@
+package converters
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A Gap is a run of missing bars between two consecutive records.
+type Gap struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Missing int       `json:"missing"`
+}
+
+// A RowIssue is a record that is a duplicate of or older than the record before it.
+type RowIssue struct {
+	Line     int       `json:"line"`
+	Time     time.Time `json:"time"`
+	Previous time.Time `json:"previous"`
+}
+
+type GapReport struct {
+	Path       string     `json:"path"`
+	Bars       int        `json:"bars"`
+	Gaps       []Gap      `json:"gaps"`
+	Duplicates []RowIssue `json:"duplicates"`
+	OutOfOrder []RowIssue `json:"outOfOrder"`
+}
+
+func (r GapReport) HasIssues() bool {
+	return len(r.Gaps) > 0 || len(r.Duplicates) > 0 || len(r.OutOfOrder) > 0
+}
+
+// GapChecker validates bar times in file order. Intraday bars are only expected
+// to be contiguous within a trading day, so the overnight and weekend breaks
+// are not reported. Daily bars are expected on every weekday.
+type GapChecker struct {
+	period  time.Duration
+	pending []pendingBar
+	last    time.Time
+	report  GapReport
+}
+
+type pendingBar struct {
+	line int
+	t    time.Time
+}
+
+// inferBars is the number of bars the period is inferred from.
+const inferBars = 10
+
+// NewGapChecker returns a checker expecting bars of the given period. With a
+// period of 0 the smallest step between the first bars is taken as the period,
+// so 5-minute files are not reported with four missing bars between each.
+func NewGapChecker(inputPath string, period time.Duration) *GapChecker {
+	return &GapChecker{period: period, report: GapReport{Path: inputPath}}
+}
+
+// Add checks the bar at the given line of the input file.
+func (g *GapChecker) Add(line int, t time.Time) {
+	if g.period == 0 {
+		g.pending = append(g.pending, pendingBar{line, t})
+		if len(g.pending) >= inferBars {
+			g.infer()
+		}
+		return
+	}
+	g.add(line, t)
+}
+
+// infer sets the period to the smallest step between the pending bars, a
+// minute if there is none, and checks them.
+func (g *GapChecker) infer() {
+	for i := 1; i < len(g.pending); i++ {
+		if step := g.pending[i].t.Sub(g.pending[i-1].t); step > 0 && (g.period == 0 || step < g.period) {
+			g.period = step
+		}
+	}
+	if g.period == 0 {
+		g.period = time.Minute
+	}
+
+	pending := g.pending
+	g.pending = nil
+	for _, bar := range pending {
+		g.add(bar.line, bar.t)
+	}
+}
+
+func (g *GapChecker) add(line int, t time.Time) {
+	g.report.Bars++
+	if g.report.Bars == 1 {
+		g.last = t
+		return
+	}
+
+	switch {
+	case t.Equal(g.last):
+		g.report.Duplicates = append(g.report.Duplicates, RowIssue{line, t, g.last})
+		return
+	case t.Before(g.last):
+		g.report.OutOfOrder = append(g.report.OutOfOrder, RowIssue{line, t, g.last})
+		return
+	}
+
+	if missing := g.missing(g.last, t); missing > 0 {
+		g.report.Gaps = append(g.report.Gaps, Gap{g.last, t, missing})
+	}
+	g.last = t
+}
+
+func (g *GapChecker) missing(from time.Time, to time.Time) int {
+	if g.period >= 24*time.Hour {
+		missing := 0
+		for d := from.AddDate(0, 0, 1); d.Before(to) && !sameDay(d, to); d = d.AddDate(0, 0, 1) {
+			if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+				missing++
+			}
+		}
+		return missing
+	}
+
+	if !sameDay(from, to) {
+		return 0
+	}
+	return int(to.Sub(from)/g.period) - 1
+}
+
+func (g *GapChecker) Report() GapReport {
+	if g.period == 0 {
+		g.infer()
+	}
+	return g.report
+}
+
+func sameDay(a time.Time, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+func (r GapReport) WriteText(w io.Writer) error {
+	const layout = "2006-01-02 15:04"
+	_, err := fmt.Fprintf(w, "%v: %v bars, %v gaps, %v duplicates, %v out of order\n",
+		r.Path, r.Bars, len(r.Gaps), len(r.Duplicates), len(r.OutOfOrder))
+	if err != nil {
+		return err
+	}
+	for _, gap := range r.Gaps {
+		if _, err := fmt.Fprintf(w, "gap %v -> %v, %v missing bars\n", gap.From.Format(layout), gap.To.Format(layout), gap.Missing); err != nil {
+			return err
+		}
+	}
+	for _, dup := range r.Duplicates {
+		if _, err := fmt.Fprintf(w, "duplicate %v at line %v\n", dup.Time.Format(layout), dup.Line); err != nil {
+			return err
+		}
+	}
+	for _, row := range r.OutOfOrder {
+		if _, err := fmt.Fprintf(w, "out of order %v at line %v after %v\n", row.Time.Format(layout), row.Line, row.Previous.Format(layout)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteGapReportFile writes the report as both name.json and name.txt,
+// creating their directory if needed.
+func WriteGapReportFile(report GapReport, name string) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("Unable to write gap report for %v", report.Path))
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("Unable to encode gap report for %v", report.Path))
+	}
+	if err := ioutil.WriteFile(name+".json", data, 0644); err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("Unable to write gap report for %v", report.Path))
+	}
+
+	file, err := os.Create(name + ".txt")
+	if err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("Unable to write gap report for %v", report.Path))
+	}
+	defer file.Close()
+	if err := report.WriteText(file); err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("Unable to write gap report for %v", report.Path))
+	}
+	return file.Close()
+}
@@ -0,0 +1,97 @@
+package converters
+
+import (
+	"fmt"
+	"github.com/dan-lind/t6converter/model"
+	"io"
+	"math"
+	"time"
+)
+
+// T6Stats summarises the contents of a t6 file.
+type T6Stats struct {
+	Records    int
+	First      time.Time // oldest bar
+	Last       time.Time // newest bar
+	Descending bool      // every bar is older than the one before it, as Zorro expects
+	Misordered int       // bars not older than the bar before them
+	MinPrice   float32
+	MaxPrice   float32
+	ZeroVolume float64       // fraction of bars without volume
+	Period     time.Duration // most common distance between bars, zero if unknown
+}
+
+// Inspect computes the statistics of records in file order.
+func Inspect(records []model.ZorroT6) T6Stats {
+	stats := T6Stats{Records: len(records), Descending: true}
+	if len(records) == 0 {
+		return stats
+	}
+
+	first, last := records[0].Date, records[0].Date
+	stats.MinPrice, stats.MaxPrice = float32(math.Inf(1)), float32(math.Inf(-1))
+	zero := 0
+	periods := make(map[time.Duration]int)
+
+	for i, record := range records {
+		first = math.Min(first, record.Date)
+		last = math.Max(last, record.Date)
+		for _, price := range []float32{record.Open, record.High, record.Low, record.Close} {
+			if price < stats.MinPrice {
+				stats.MinPrice = price
+			}
+			if price > stats.MaxPrice {
+				stats.MaxPrice = price
+			}
+		}
+		if record.Vol == 0 {
+			zero++
+		}
+
+		if i > 0 {
+			previous := records[i-1].Date
+			if record.Date >= previous {
+				stats.Descending = false
+				stats.Misordered++
+			}
+			distance := ConvertFromOle(previous).Sub(ConvertFromOle(record.Date))
+			if distance < 0 {
+				distance = -distance
+			}
+			if distance > 0 {
+				periods[distance]++
+			}
+		}
+	}
+
+	stats.First = ConvertFromOle(first)
+	stats.Last = ConvertFromOle(last)
+	stats.ZeroVolume = float64(zero) / float64(len(records))
+	for period, count := range periods {
+		if count > periods[stats.Period] || (count == periods[stats.Period] && period < stats.Period) {
+			stats.Period = period
+		}
+	}
+	return stats
+}
+
+func (s T6Stats) WriteText(w io.Writer) error {
+	if s.Records == 0 {
+		_, err := fmt.Fprintln(w, "records:     0")
+		return err
+	}
+
+	const layout = "2006-01-02 15:04:05"
+	order := "descending"
+	if !s.Descending {
+		order = fmt.Sprintf("not descending, %v bars out of order", s.Misordered)
+	}
+	period := "unknown"
+	if s.Period > 0 {
+		period = s.Period.String()
+	}
+
+	_, err := fmt.Fprintf(w, "records:     %v\nfirst bar:   %v\nlast bar:    %v\norder:       %v\nprices:      %v - %v\nzero volume: %.1f%%\nbar period:  %v\n",
+		s.Records, s.First.Format(layout), s.Last.Format(layout), order, s.MinPrice, s.MaxPrice, s.ZeroVolume*100, period)
+	return err
+}
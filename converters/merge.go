@@ -0,0 +1,82 @@
+package converters
+
+import (
+	"github.com/dan-lind/t6converter/model"
+	"github.com/pkg/errors"
+	"math"
+	"sort"
+)
+
+// A MergePolicy decides what happens to existing t6 files when writing.
+type MergePolicy int
+
+const (
+	Overwrite MergePolicy = iota // replace existing files
+	KeepOld                      // merge, keeping the existing bar when both have the same date
+	KeepNew                      // merge, replacing the existing bar when both have the same date
+	Combine                      // merge, combining bars with the same date into one, the existing bar first
+)
+
+// ParseMergePolicy parses the -merge flag, an empty string means Overwrite.
+func ParseMergePolicy(policy string) (MergePolicy, error) {
+	switch policy {
+	case "":
+		return Overwrite, nil
+	case "keep-old":
+		return KeepOld, nil
+	case "keep-new":
+		return KeepNew, nil
+	}
+	return Overwrite, errors.Errorf("Unknown merge policy %v, expected keep-old or keep-new", policy)
+}
+
+// Merge combines existing and new bars, keeping a single bar per OLE date as
+// decided by policy. Combine treats the bars of a date as the parts of one
+// bar, such as the parts of a week read from the files of two years, taking
+// the open of the existing bar and the close of the new one. Duplicates among
+// the new bars keep the last one. The result is sorted newest first like Zorro
+// expects.
+func Merge(existing []model.ZorroT6, records []model.ZorroT6, policy MergePolicy) []model.ZorroT6 {
+	byDate := make(map[float64]model.ZorroT6, len(existing)+len(records))
+	for _, record := range existing {
+		byDate[record.Date] = record
+	}
+
+	added := make(map[float64]bool, len(records))
+	for _, record := range records {
+		if old, ok := byDate[record.Date]; ok && !added[record.Date] {
+			switch policy {
+			case KeepOld:
+				continue
+			case Combine:
+				record = combine(old, record)
+			}
+		}
+		byDate[record.Date] = record
+		added[record.Date] = true
+	}
+
+	merged := make([]model.ZorroT6, 0, len(byDate))
+	for _, record := range byDate {
+		merged = append(merged, record)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Date > merged[j].Date
+	})
+	return merged
+}
+
+// combine returns the bar made of the parts first and then.
+func combine(first model.ZorroT6, then model.ZorroT6) model.ZorroT6 {
+	bar := then
+	bar.Open = first.Open
+	if first.High > bar.High {
+		bar.High = first.High
+	}
+	if first.Low < bar.Low {
+		bar.Low = first.Low
+	}
+	bar.Vol = int32(math.Min(float64(first.Vol)+float64(then.Vol), math.MaxInt32))
+	bar.Val = (first.Val + then.Val) / 2
+	return bar
+}
@@ -0,0 +1,184 @@
+package converters
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/dan-lind/t6converter/model"
+	"github.com/pkg/errors"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// HstHeader is the 148 byte header of a MetaTrader 4 history file.
+type HstHeader struct {
+	Version   int32
+	Copyright [64]byte
+	Symbol    [12]byte
+	Period    int32 // minutes
+	Digits    int32
+	TimeSign  int32
+	LastSync  int32
+	Unused    [13]int32
+}
+
+// hstBar400 is a bar of a version 400 history file, volume counts ticks.
+type hstBar400 struct {
+	Time   int32
+	Open   float64
+	Low    float64
+	High   float64
+	Close  float64
+	Volume float64
+}
+
+// hstBar401 is a bar of a version 401 history file, spread is in points.
+type hstBar401 struct {
+	Time       int64
+	Open       float64
+	High       float64
+	Low        float64
+	Close      float64
+	TickVolume int64
+	Spread     int32
+	RealVolume int64
+}
+
+// SymbolName returns the symbol of the header without its padding.
+func (h HstHeader) SymbolName() string {
+	return string(bytes.TrimRight(h.Symbol[:], "\x00"))
+}
+
+func ReadHst(r io.Reader, daily bool) (HstHeader, map[int][]model.ZorroT6, error) {
+	return Parser{}.ReadHst(r, daily)
+}
+
+// ReadHst reads the bars of a version 400 or 401 MetaTrader 4 history file,
+// grouped by year or, when daily, into a single group with key 0. Version 401
+// bars keep their spread in Val, converted from points to a price. Bar times
+// are the broker's server time, converted by the parser's zones, the profile is
+// not used. MetaTrader stamps bars at their open, intraday bars are moved
+// forward by the period of the header to be stamped at their close, daily bars
+// keep their date. The parser's gap checker receives the time of every bar
+// with its number as the line and checks for the period of the header.
+func (p Parser) ReadHst(r io.Reader, daily bool) (HstHeader, map[int][]model.ZorroT6, error) {
+	br := bufio.NewReader(r)
+
+	var header HstHeader
+	if err := binary.Read(br, binary.LittleEndian, &header); err != nil {
+		return HstHeader{}, nil, errors.WithMessage(err, "Unable to read hst header")
+	}
+
+	period := time.Duration(header.Period) * time.Minute
+	var shift time.Duration
+	if !daily {
+		shift = period
+	}
+	var next func() (model.ZorroT6, time.Time, error)
+	switch header.Version {
+	case 400:
+		next = func() (model.ZorroT6, time.Time, error) {
+			var bar hstBar400
+			if err := binary.Read(br, binary.LittleEndian, &bar); err != nil {
+				return model.ZorroT6{}, time.Time{}, err
+			}
+			t := p.Zones.Convert(time.Unix(int64(bar.Time), 0).UTC()).Add(shift)
+			return model.ZorroT6{
+				Date:  ConvertToOle(t),
+				Open:  float32(bar.Open),
+				High:  float32(bar.High),
+				Low:   float32(bar.Low),
+				Close: float32(bar.Close),
+				Vol:   int32(math.Min(bar.Volume, math.MaxInt32)),
+			}, t, nil
+		}
+	case 401:
+		point := math.Pow(10, -float64(header.Digits))
+		next = func() (model.ZorroT6, time.Time, error) {
+			var bar hstBar401
+			if err := binary.Read(br, binary.LittleEndian, &bar); err != nil {
+				return model.ZorroT6{}, time.Time{}, err
+			}
+			t := p.Zones.Convert(time.Unix(bar.Time, 0).UTC()).Add(shift)
+			return model.ZorroT6{
+				Date:  ConvertToOle(t),
+				Open:  float32(bar.Open),
+				High:  float32(bar.High),
+				Low:   float32(bar.Low),
+				Close: float32(bar.Close),
+				Val:   float32(float64(bar.Spread) * point),
+				Vol:   int32(math.Min(float64(bar.TickVolume), math.MaxInt32)),
+			}, t, nil
+		}
+	default:
+		return HstHeader{}, nil, errors.Errorf("Unsupported hst version %v", header.Version)
+	}
+
+	if p.Gaps != nil && period > 0 {
+		p.Gaps.period = period
+	}
+
+	var t6records = make(map[int][]model.ZorroT6)
+	for i := 0; ; i++ {
+		t6, parsedTime, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return HstHeader{}, nil, errors.WithMessage(err, fmt.Sprintf("Failed to read hst bar %v", i))
+		}
+		if p.Gaps != nil {
+			p.Gaps.Add(i+1, parsedTime)
+		}
+
+		key := parsedTime.Year()
+		if daily {
+			key = 0
+		}
+		t6records[key] = append(t6records[key], t6)
+	}
+
+	if daily && len(t6records) == 0 {
+		t6records[0] = nil
+	}
+	return header, t6records, nil
+}
+
+func HstFileToStruct(path string, daily bool) (map[int][]model.ZorroT6, error) {
+	return Parser{}.HstFileToStruct(path, daily)
+}
+
+// HstFileSymbol returns the symbol in the header of the MetaTrader 4 history
+// file at path. MetaTrader names the files after symbol and period, e.g.
+// EURUSD60.hst, so the file name does not give the symbol.
+func HstFileSymbol(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", errors.WithMessage(err, fmt.Sprintf("Unable to read file with path %v", path))
+	}
+	defer file.Close()
+
+	var header HstHeader
+	if err := binary.Read(file, binary.LittleEndian, &header); err != nil {
+		return "", errors.WithMessage(err, fmt.Sprintf("Unable to read hst header of file %v", path))
+	}
+	return header.SymbolName(), nil
+}
+
+// HstFileToStruct reads the MetaTrader 4 history file at path, see ReadHst.
+func (p Parser) HstFileToStruct(path string, daily bool) (map[int][]model.ZorroT6, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("Unable to read file with path %v", path))
+	}
+	defer file.Close()
+
+	_, records, err := p.ReadHst(file, daily)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("Unable to read file with path %v", path))
+	}
+	return records, nil
+}
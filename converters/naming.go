@@ -0,0 +1,120 @@
+package converters
+
+import (
+	"github.com/pkg/errors"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A NameTemplate names the files written for an input, relative to the output
+// directory. {symbol} is replaced with the symbol of the input, {year} with the
+// year of the records, 0 for daily bars, and {dir} with the directory of the
+// input relative to the input directory. Records of all years that end up with
+// the same name are written to a single file.
+type NameTemplate string
+
+// The default templates, named like Zorro's own history files.
+const (
+	YearlyT6 NameTemplate = "{symbol}_{year}.t6"
+	DailyT6  NameTemplate = "{symbol}.t6"
+	YearlyT1 NameTemplate = "{symbol}_{year}.t1"
+	YearlyT8 NameTemplate = "{symbol}_{year}.t8"
+)
+
+var placeholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// ParseNameTemplate checks that template only uses known placeholders and
+// stays within the output directory.
+func ParseNameTemplate(template string) (NameTemplate, error) {
+	if strings.TrimSpace(template) == "" {
+		return "", errors.New("Name template is empty")
+	}
+	for _, p := range placeholder.FindAllString(template, -1) {
+		switch p {
+		case "{symbol}", "{year}", "{dir}":
+		default:
+			return "", errors.Errorf("Unknown placeholder %v in name template %v", p, template)
+		}
+	}
+
+	name := filepath.ToSlash(template)
+	if filepath.IsAbs(template) || strings.HasPrefix(name, "/") {
+		return "", errors.Errorf("Name template %v must be relative to the output directory", template)
+	}
+	for _, element := range strings.Split(name, "/") {
+		if element == ".." {
+			return "", errors.Errorf("Name template %v must stay within the output directory", template)
+		}
+	}
+	return NameTemplate(template), nil
+}
+
+// Name returns the file name for the records of year of symbol, read from an
+// input in dir.
+func (t NameTemplate) Name(dir string, symbol string, year int) string {
+	if dir == "" {
+		dir = "."
+	}
+	r := strings.NewReplacer("{symbol}", symbol, "{year}", strconv.Itoa(year), "{dir}", filepath.ToSlash(dir))
+	return filepath.Clean(filepath.FromSlash(r.Replace(string(t))))
+}
+
+// inputExtensions are the extensions Symbol strips, repeatedly so that e.g.
+// EURUSD.csv.gz becomes EURUSD.
+var inputExtensions = map[string]bool{
+	".txt": true, ".csv": true, ".gz": true, ".bz2": true, ".zip": true, ".hst": true, ".bi5": true,
+}
+
+// vendorSuffix matches the resolution, content and period suffixes vendors
+// append to symbols, e.g. _1min, -5m, MetaTrader's _M1 or _H4, _daily, _ticks,
+// a year like _2014 or a month like _201401, which HistData glues to its _M1,
+// and the from and to minutes of MetaTrader 5 exports like _201401020000.
+var vendorSuffix = regexp.MustCompile(`(?i)[_\-. ](\d+(s|sec|secs|m|min|mins|minute|minutes|h|hour|hours|d|day|days)|m\d{1,2}|h\d{1,2}|d1|w1|mn1|daily|intraday|tick|ticks|trades|quotes|bars|(m1)?(19|20)\d\d(0[1-9]|1[0-2])?|(19|20)\d{10})$`)
+
+// vendorPrefix matches the prefixes HistData puts before symbols, e.g.
+// DAT_ASCII_EURUSD_M1_2014.csv in HISTDATA_COM_ASCII_EURUSD_M1201401.zip.
+var vendorPrefix = regexp.MustCompile(`(?i)^(dat|histdata_com)_(ascii|mt|xlsx|nt|metastock)_`)
+
+// Symbol returns the symbol of the input file at path, its base name without
+// input extensions, vendor suffixes and prefixes. Dots elsewhere in the path
+// are kept.
+func Symbol(path string) string {
+	name := filepath.Base(path)
+	for {
+		ext := filepath.Ext(name)
+		if ext == name || !inputExtensions[strings.ToLower(ext)] {
+			break
+		}
+		name = strings.TrimSuffix(name, ext)
+	}
+	for {
+		stripped := vendorSuffix.ReplaceAllString(name, "")
+		if stripped == name || stripped == "" {
+			break
+		}
+		name = stripped
+	}
+	if stripped := vendorPrefix.ReplaceAllString(name, ""); stripped != "" {
+		name = stripped
+	}
+	return name
+}
+
+// SymbolMatching returns the first submatch of pattern in the base name of
+// path, or the whole match if pattern has no groups, falling back to Symbol
+// when it does not match.
+func SymbolMatching(pattern *regexp.Regexp, path string) string {
+	if pattern != nil {
+		if match := pattern.FindStringSubmatch(filepath.Base(path)); match != nil {
+			if len(match) > 1 && match[1] != "" {
+				return match[1]
+			}
+			if match[0] != "" {
+				return match[0]
+			}
+		}
+	}
+	return Symbol(path)
+}
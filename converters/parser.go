@@ -0,0 +1,57 @@
+package converters
+
+import (
+	"time"
+)
+
+// A Parser converts csv records to T6 records. The zero value parses the
+// Pitrading layouts with UTC timestamps and does not check for gaps, which is
+// what the package level functions use.
+type Parser struct {
+	Profile Profile // zero value selects the Pitrading layout of the method called
+	Zones   Zones
+	Gaps    *GapChecker // optional, receives the time of every parsed bar
+}
+
+func (p Parser) profile(fallback Profile) Profile {
+	if p.Profile == (Profile{}) {
+		return fallback
+	}
+	return p.Profile
+}
+
+// Zones holds the zone timestamps in the source data are given in and the zone
+// the OLE dates in the written files represent. Zorro expects UTC. A nil
+// location means UTC.
+type Zones struct {
+	In  *time.Location
+	Out *time.Location
+}
+
+// Parse parses value as wall clock time in the input zone and returns the
+// wall clock time of the same instant in the output zone, expressed in UTC so
+// that ConvertToOle stores it unchanged. Daylight saving transitions are
+// handled by the input zone, e.g. 09:30 in America/New_York is 14:30 UTC in
+// winter and 13:30 UTC in summer.
+func (z Zones) Parse(layout string, value string) (time.Time, error) {
+	parsedTime, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return z.Convert(parsedTime), nil
+}
+
+// Convert converts the wall clock time of t, read in the input zone whatever
+// the location of t, like Parse does with a parsed value.
+func (z Zones) Convert(t time.Time) time.Time {
+	in, out := time.UTC, time.UTC
+	if z.In != nil {
+		in = z.In
+	}
+	if z.Out != nil {
+		out = z.Out
+	}
+
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), in).In(out)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
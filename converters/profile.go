@@ -0,0 +1,208 @@
+package converters
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// A Profile describes the layout of a csv input file. Columns are numbered
+// from 1, 0 means the file does not have the column. When Time is set the time
+// column is appended to the date column before parsing with DateLayout. Zone
+// is the IANA zone of the timestamps, used unless the parser is given one.
+// Period is the bar period, e.g. 5m. The gap checker infers it from the first
+// bars when empty.
+//
+// Bars are stamped at their close, which is the time Zorro expects. Stamp is
+// "open" for intraday files that stamp bars at their open, they are moved
+// forward by Period, so the 09:30 bar of a 1-minute file is read as 09:31.
+// Files stamping bars at their close, and daily files, whose bars keep their
+// date, leave Stamp empty.
+//
+// Val holds the spread of the bar, read from the Spread column or else averaged
+// over the bid columns, which files quoting both sides have next to the ask
+// prices in Open to Close.
+type Profile struct {
+	Delimiter  string `json:"delimiter"`  // a single character, "tab" or empty for a comma
+	Header     bool   `json:"header"`     // skip the first line
+	DateLayout string `json:"dateLayout"` // Go time layout of date and time, e.g. 2006010215:04
+	Date       int    `json:"date"`
+	Time       int    `json:"time"`
+	Open       int    `json:"open"`
+	High       int    `json:"high"`
+	Low        int    `json:"low"`
+	Close      int    `json:"close"`
+	Volume     int    `json:"volume"`
+	Spread     int    `json:"spread"`
+	BidOpen    int    `json:"bidOpen"`
+	BidHigh    int    `json:"bidHigh"`
+	BidLow     int    `json:"bidLow"`
+	BidClose   int    `json:"bidClose"`
+	Zone       string `json:"zone"`   // empty for UTC
+	Period     string `json:"period"` // see ParsePeriod
+	Stamp      string `json:"stamp"`  // open or empty for close
+}
+
+// Pitrading1min is the layout of Pitrading 1-minute files, e.g.
+// 20140102,09:30,38.88,38.88,38.82,38.85,67004
+// stamped at the open of the bar.
+var Pitrading1min = Profile{DateLayout: "2006010215:04", Date: 1, Time: 2, Open: 3, High: 4, Low: 5, Close: 6, Volume: 7, Period: "1m", Stamp: "open"}
+
+// PitradingDaily is the layout of Pitrading daily files, a header followed by
+// e.g. 20010511,420.81,421.36,418.97,419.64,0
+var PitradingDaily = Profile{Header: true, DateLayout: "20060102", Date: 1, Open: 2, High: 3, Low: 4, Close: 5, Volume: 6}
+
+// HistData is the layout of HistData.com's generic ASCII 1-minute bars, e.g.
+// 20140102 170000;1.376100;1.376200;1.375900;1.376000;0
+// Their timestamps are the open of the bar in EST all year round, without
+// daylight saving time.
+var HistData = Profile{Delimiter: ";", DateLayout: "20060102 150405", Date: 1, Open: 2, High: 3, Low: 4, Close: 5, Volume: 6, Zone: "Etc/GMT+5", Period: "1m", Stamp: "open"}
+
+// MT5 is the layout of bars exported from MetaTrader 5, a tab separated header
+// <DATE> <TIME> <OPEN> <HIGH> <LOW> <CLOSE> <TICKVOL> <VOL> <SPREAD> followed by
+// e.g. 2014.01.02 13:00:00 1.37610 1.37620 1.37590 1.37600 118 0 12
+// Volume is the tick volume, the spread in points is left out. The timestamps
+// are the open of the bar in the broker's server time. The period is that of
+// the default M1 export, other timeframes need theirs, e.g. -period 1h.
+var MT5 = Profile{Delimiter: "tab", Header: true, DateLayout: "2006.01.0215:04:05", Date: 1, Time: 2, Open: 3, High: 4, Low: 5, Close: 6, Volume: 7, Period: "1m", Stamp: "open"}
+
+// MT5Daily is the layout of daily bars exported from MetaTrader 5, which have
+// no <TIME> column.
+var MT5Daily = Profile{Delimiter: "tab", Header: true, DateLayout: "2006.01.02", Date: 1, Open: 2, High: 3, Low: 4, Close: 5, Volume: 6}
+
+// Profiles are the built-in profiles selectable by name.
+var Profiles = map[string]Profile{
+	"pitrading":       Pitrading1min,
+	"pitrading-daily": PitradingDaily,
+	"histdata":        HistData,
+	"mt5":             MT5,
+	"mt5-daily":       MT5Daily,
+}
+
+// LoadProfile returns the built-in profile called name, or else reads a json
+// profile from the file at name.
+func LoadProfile(name string) (Profile, error) {
+	if profile, ok := Profiles[name]; ok {
+		return profile, nil
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return Profile{}, errors.WithMessage(err, fmt.Sprintf("Unknown profile %v", name))
+	}
+	var profile Profile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return Profile{}, errors.WithMessage(err, fmt.Sprintf("Unable to read profile %v", name))
+	}
+	return profile, profile.Validate()
+}
+
+// WithColumns returns a copy of the profile with the columns in spec replaced.
+// The spec is a comma separated list such as date=1,time=2,open=3,volume=0.
+func (p Profile) WithColumns(spec string) (Profile, error) {
+	columns := map[string]*int{
+		"date": &p.Date, "time": &p.Time, "open": &p.Open, "high": &p.High,
+		"low": &p.Low, "close": &p.Close, "volume": &p.Volume, "spread": &p.Spread,
+		"bidopen": &p.BidOpen, "bidhigh": &p.BidHigh, "bidlow": &p.BidLow, "bidclose": &p.BidClose,
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		kv := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(kv) != 2 {
+			return p, errors.Errorf("Invalid column mapping %v", entry)
+		}
+		column, ok := columns[strings.ToLower(kv[0])]
+		if !ok {
+			return p, errors.Errorf("Unknown column %v", kv[0])
+		}
+		n, err := strconv.Atoi(kv[1])
+		if err != nil || n < 0 {
+			return p, errors.Errorf("Invalid column number %v for %v", kv[1], kv[0])
+		}
+		*column = n
+	}
+	return p, p.Validate()
+}
+
+func (p Profile) Validate() error {
+	if p.DateLayout == "" {
+		return errors.New("Profile is missing a date layout")
+	}
+	if p.Date == 0 || p.Open == 0 || p.High == 0 || p.Low == 0 || p.Close == 0 {
+		return errors.New("Profile needs date, open, high, low and close columns")
+	}
+	if p.Delimiter != "" && p.Delimiter != "tab" && utf8.RuneCountInString(p.Delimiter) != 1 {
+		return errors.Errorf("Invalid delimiter %v", p.Delimiter)
+	}
+	if _, err := p.location(); err != nil {
+		return err
+	}
+	if _, err := p.stampShift(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// BarPeriod returns the period of the bars, 0 if it is not known.
+func (p Profile) BarPeriod() (time.Duration, error) {
+	if p.Period == "" {
+		return 0, nil
+	}
+	return ParsePeriod(p.Period)
+}
+
+// stampShift returns how far the bars of the profile are moved forward to be
+// stamped at their close.
+func (p Profile) stampShift() (time.Duration, error) {
+	period, err := p.BarPeriod()
+	if err != nil {
+		return 0, err
+	}
+	switch strings.ToLower(p.Stamp) {
+	case "", "close":
+		return 0, nil
+	case "open":
+		if period == 0 {
+			return 0, errors.New("Profile stamps bars at their open, it needs a period")
+		}
+		return period, nil
+	}
+	return 0, errors.Errorf("Invalid stamp %v, expected open or close", p.Stamp)
+}
+
+// location returns the zone of the profile, nil for UTC.
+func (p Profile) location() (*time.Location, error) {
+	if p.Zone == "" {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(p.Zone)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("Invalid zone %v", p.Zone))
+	}
+	return loc, nil
+}
+
+// Comma returns the field delimiter for csv.Reader.
+func (p Profile) Comma() rune {
+	switch p.Delimiter {
+	case "":
+		return ','
+	case "tab":
+		return '\t'
+	}
+	r, _ := utf8.DecodeRuneInString(p.Delimiter)
+	return r
+}
+
+// field returns the trimmed value of a 1-based column, or an empty string if
+// the column is not mapped or missing from the record.
+func field(record []string, column int) string {
+	if column < 1 || column > len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[column-1])
+}
@@ -0,0 +1,166 @@
+package converters
+
+import (
+	"github.com/dan-lind/t6converter/model"
+	"github.com/pkg/errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParsePeriod parses a bar period such as 5m, 1h or 1d. Days are not supported
+// by time.ParseDuration so they are handled here.
+func ParsePeriod(period string) (time.Duration, error) {
+	if strings.HasSuffix(period, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(period, "d"))
+		if err != nil || days <= 0 {
+			return 0, errors.Errorf("Invalid period %v", period)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return 0, errors.Errorf("Invalid period %v", period)
+	}
+	return d, nil
+}
+
+// Resample aggregates records into bars of the given period. Records are
+// expected to be stamped at their close, like all bars the converter reads, so
+// the record of 09:59 to 10:00 ends the 09:00 to 10:00 bar, which is stamped
+// 10:00. Each bar takes the open of its first record, the highest high, the
+// lowest low, the close of its last record and the summed volume. Val is
+// averaged. The returned bars are sorted oldest first.
+func Resample(records []model.ZorroT6, period time.Duration) []model.ZorroT6 {
+	if len(records) == 0 {
+		return records
+	}
+	r := NewResampler(period)
+	return append(r.Add(records), r.Flush()...)
+}
+
+// A Resampler resamples records that arrive in chunks, such as the years of
+// StreamBars, see Resample. The bar being built is carried from one chunk to
+// the next, so a bar spanning two chunks is built once.
+type Resampler struct {
+	period time.Duration
+	end    time.Time
+	bar    model.ZorroT6
+	vol    int64
+	val    float64
+	count  int
+}
+
+func NewResampler(period time.Duration) *Resampler {
+	return &Resampler{period: period}
+}
+
+// Add sorts a copy of records and returns the bars they finish, oldest first.
+func (r *Resampler) Add(records []model.ZorroT6) []model.ZorroT6 {
+	sorted := make([]model.ZorroT6, len(records))
+	copy(sorted, records)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date < sorted[j].Date
+	})
+
+	var bars []model.ZorroT6
+	for _, record := range sorted {
+		barEnd := closeOf(ConvertFromOle(record.Date), r.period)
+		if r.count == 0 || !barEnd.Equal(r.end) {
+			bars = append(bars, r.Flush()...)
+			r.end = barEnd
+			r.bar = model.ZorroT6{Date: ConvertToOle(barEnd), Open: record.Open, High: record.High, Low: record.Low}
+		}
+
+		if record.High > r.bar.High {
+			r.bar.High = record.High
+		}
+		if record.Low < r.bar.Low {
+			r.bar.Low = record.Low
+		}
+		r.bar.Close = record.Close
+		r.vol += int64(record.Vol)
+		r.val += float64(record.Val)
+		r.count++
+	}
+	return bars
+}
+
+// Flush finishes the bar being built and returns it, nil if there is none.
+func (r *Resampler) Flush() []model.ZorroT6 {
+	if r.count == 0 {
+		return nil
+	}
+	bar := r.bar
+	bar.Vol = int32(math.Min(float64(r.vol), math.MaxInt32))
+	bar.Val = float32(r.val / float64(r.count))
+	r.bar, r.vol, r.val, r.count = model.ZorroT6{}, 0, 0, 0
+	return []model.ZorroT6{bar}
+}
+
+// AddYears adds the records of each year in year order and returns the bars
+// they finish grouped by the year of their close time, so the last bar of a
+// year can move into the next one. Daily bars stay under key 0.
+func (r *Resampler) AddYears(recordMap map[int][]model.ZorroT6, daily bool) map[int][]model.ZorroT6 {
+	years := make([]int, 0, len(recordMap))
+	for year := range recordMap {
+		years = append(years, year)
+	}
+	sort.Ints(years)
+
+	resampled := make(map[int][]model.ZorroT6)
+	for _, year := range years {
+		if daily {
+			// keep the key of daily files without bars, which are written empty
+			resampled[year] = append(resampled[year], r.Add(recordMap[year])...)
+			continue
+		}
+		resampled = groupByClose(resampled, r.Add(recordMap[year]), daily)
+	}
+	return resampled
+}
+
+// FlushYears finishes the bar being built and returns it grouped like the
+// bars of AddYears.
+func (r *Resampler) FlushYears(daily bool) map[int][]model.ZorroT6 {
+	return groupByClose(make(map[int][]model.ZorroT6), r.Flush(), daily)
+}
+
+// OpenYear returns the year the bar being built closes in, false if there is
+// none. No bar finished later closes in an earlier year.
+func (r *Resampler) OpenYear() (int, bool) {
+	return r.end.Year(), r.count > 0
+}
+
+func groupByClose(recordMap map[int][]model.ZorroT6, bars []model.ZorroT6, daily bool) map[int][]model.ZorroT6 {
+	for _, bar := range bars {
+		year := 0
+		if !daily {
+			year = ConvertFromOle(bar.Date).Year()
+		}
+		recordMap[year] = append(recordMap[year], bar)
+	}
+	return recordMap
+}
+
+// closeOf returns the end of the period that a bar stamped t closes.
+func closeOf(t time.Time, period time.Duration) time.Time {
+	end := t.Truncate(period)
+	if end.Before(t) {
+		end = end.Add(period)
+	}
+	return end
+}
+
+// ResampleYears resamples the bars of all years, see Resampler.AddYears.
+func ResampleYears(recordMap map[int][]model.ZorroT6, period time.Duration, daily bool) map[int][]model.ZorroT6 {
+	r := NewResampler(period)
+	resampled := r.AddYears(recordMap, daily)
+	for year, bars := range r.FlushYears(daily) {
+		resampled[year] = append(resampled[year], bars...)
+	}
+	return resampled
+}
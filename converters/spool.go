@@ -0,0 +1,115 @@
+package converters
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/dan-lind/t6converter/model"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// A TickSpool collects ticks in time order for a t1 file without holding all
+// of them in memory. The ticks of the current day are kept, those of the days
+// before are spooled to a temporary file per day, which are joined newest
+// first when the t1 file is written.
+type TickSpool struct {
+	dir   string   // of the temporary files, created for the first day spooled
+	days  []string // the temporary files, oldest first
+	day   int      // of ticks, as OLE date
+	ticks []model.ZorroT1
+}
+
+func NewTickSpool() *TickSpool {
+	return &TickSpool{}
+}
+
+// Add adds ticks, which must be newer than the ticks added before.
+func (s *TickSpool) Add(ticks ...model.ZorroT1) error {
+	for _, tick := range ticks {
+		day := int(math.Floor(tick.Date))
+		if len(s.ticks) > 0 && day != s.day {
+			if err := s.spool(); err != nil {
+				return err
+			}
+		}
+		s.day = day
+		s.ticks = append(s.ticks, tick)
+	}
+	return nil
+}
+
+func (s *TickSpool) spool() error {
+	if s.dir == "" {
+		dir, err := ioutil.TempDir("", "t6converter-ticks")
+		if err != nil {
+			return errors.WithMessage(err, "Unable to spool ticks")
+		}
+		s.dir = dir
+	}
+
+	name := filepath.Join(s.dir, fmt.Sprintf("%v.t1", len(s.days)))
+	file, err := os.Create(name)
+	if err != nil {
+		return errors.WithMessage(err, "Unable to spool ticks")
+	}
+	defer file.Close()
+	buf := bufio.NewWriter(file)
+	if err := writeAllRecords(s.ticks, buf); err != nil {
+		return errors.WithMessage(err, "Unable to spool ticks")
+	}
+	if err := buf.Flush(); err != nil {
+		return errors.WithMessage(err, "Unable to spool ticks")
+	}
+	if err := file.Close(); err != nil {
+		return errors.WithMessage(err, "Unable to spool ticks")
+	}
+
+	s.days = append(s.days, name)
+	s.ticks = s.ticks[:0]
+	return nil
+}
+
+// WriteT1File writes the ticks newest first to the t1 file called name, reading
+// back one spooled day at a time. It is called once, after the last Add.
+func (s *TickSpool) WriteT1File(name string) error {
+	err := writeFileAtomic(name, func(w io.Writer) error {
+		buf := bufio.NewWriter(w)
+		if err := writeNewestFirst(s.ticks, buf); err != nil {
+			return err
+		}
+		for i := len(s.days) - 1; i >= 0; i-- {
+			ticks, err := T1FileToStruct(s.days[i])
+			if err != nil {
+				return err
+			}
+			if err := writeNewestFirst(ticks, buf); err != nil {
+				return err
+			}
+		}
+		return buf.Flush()
+	})
+	if err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("Unable to write file with path %v", name))
+	}
+	return nil
+}
+
+// writeNewestFirst reverses ticks, which are oldest first, and writes them.
+func writeNewestFirst(ticks []model.ZorroT1, w io.Writer) error {
+	for i, j := 0, len(ticks)-1; i < j; i, j = i+1, j-1 {
+		ticks[i], ticks[j] = ticks[j], ticks[i]
+	}
+	return writeAllRecords(ticks, w)
+}
+
+// Close removes the temporary files.
+func (s *TickSpool) Close() error {
+	if s.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(s.dir)
+}
@@ -0,0 +1,326 @@
+package converters
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"github.com/dan-lind/t6converter/model"
+	"github.com/pkg/errors"
+	"io"
+	"time"
+)
+
+// A recordSource returns one record per call and io.EOF at the end, like
+// csv.Reader.Read.
+type recordSource interface {
+	Read() ([]string, error)
+}
+
+type sliceSource struct {
+	records [][]string
+	next    int
+}
+
+func (s *sliceSource) Read() ([]string, error) {
+	if s.next >= len(s.records) {
+		return nil, io.EOF
+	}
+	s.next++
+	return s.records[s.next-1], nil
+}
+
+// A BarReader decodes bars one record at a time, so only the decoded 32 byte
+// bars are kept in memory rather than every field of the file.
+type BarReader struct {
+	parser Parser
+	source recordSource
+	daily  bool
+	shift  time.Duration
+	line   int
+	err    error
+}
+
+// NewBarReader returns a reader decoding csv records from r. The parser's
+// profile defaults to PitradingDaily when daily and Pitrading1min otherwise.
+func (p Parser) NewBarReader(r io.Reader, daily bool) *BarReader {
+	if daily {
+		p.Profile = p.profile(PitradingDaily)
+	} else {
+		p.Profile = p.profile(Pitrading1min)
+	}
+
+	csvReader := csv.NewReader(bufio.NewReader(r))
+	csvReader.Comma = p.Profile.Comma()
+	csvReader.ReuseRecord = true
+	return p.newBarReader(csvReader, daily)
+}
+
+func (p Parser) newBarReader(source recordSource, daily bool) *BarReader {
+	b := &BarReader{source: source, daily: daily}
+	b.parser, b.err = p.withProfileZone()
+	if b.err == nil && !daily {
+		b.shift, b.err = p.Profile.stampShift()
+	}
+	return b
+}
+
+// withProfileZone returns the parser with the profile's zone as input zone
+// unless it was given one, resolved once rather than for every record.
+func (p Parser) withProfileZone() (Parser, error) {
+	if p.Zones.In != nil {
+		return p, nil
+	}
+	loc, err := p.Profile.location()
+	if err != nil {
+		return p, err
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	p.Zones.In = loc
+	return p, nil
+}
+
+// Read returns the next bar and its close time, see Profile, or io.EOF when
+// there are no more bars.
+func (b *BarReader) Read() (model.ZorroT6, time.Time, error) {
+	if b.err != nil {
+		return model.ZorroT6{}, time.Time{}, b.err
+	}
+	for {
+		record, err := b.source.Read()
+		if err == io.EOF {
+			return model.ZorroT6{}, time.Time{}, err
+		}
+		if err != nil {
+			return model.ZorroT6{}, time.Time{}, errors.WithMessage(err, "Unable to read records")
+		}
+
+		b.line++
+		if b.line == 1 && b.parser.Profile.Header {
+			// skip header line
+			continue
+		}
+
+		t6, parsedTime, err := b.parser.RecordToStruct(record)
+		if err != nil {
+			return model.ZorroT6{}, time.Time{}, errors.WithMessage(err, fmt.Sprintf("Failed to parse time for record %v", b.line-1))
+		}
+		if b.shift > 0 {
+			parsedTime = parsedTime.Add(b.shift)
+			t6.Date = ConvertToOle(parsedTime)
+		}
+
+		if b.parser.Gaps != nil {
+			b.parser.Gaps.Add(b.line, parsedTime)
+		}
+		return t6, parsedTime, nil
+	}
+}
+
+// ReadAll reads the remaining bars grouped by year, or all of them under key 0
+// for daily bars.
+func (b *BarReader) ReadAll() (map[int][]model.ZorroT6, error) {
+	var t6records = make(map[int][]model.ZorroT6)
+	for {
+		t6, parsedTime, err := b.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		year := parsedTime.Year()
+		if b.daily {
+			year = 0
+		}
+		t6records[year] = append(t6records[year], t6)
+	}
+
+	if b.daily && len(t6records) == 0 {
+		t6records[0] = nil
+	}
+	return t6records, nil
+}
+
+// FileToStruct reads the bars of the csv file at path into memory, see
+// StreamBars.
+func (p Parser) FileToStruct(path string, daily bool) (map[int][]model.ZorroT6, error) {
+	var t6records = make(map[int][]model.ZorroT6)
+	err := p.StreamBars(path, daily, func(year int, bars []model.ZorroT6) error {
+		t6records[year] = append(t6records[year], bars...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t6records, nil
+}
+
+// StreamBars reads the bars of the csv file at path, see NewBarReader, and
+// calls write with the bars of each year as soon as the file moves on to the
+// next year, so only a year of bars is held in memory. A year that reappears
+// in an unsorted file is passed to write again. Daily bars are passed in a
+// single call with key 0, also when there are none. Compressed files are read
+// as described for InputSuffixes, the csv files of a zip archive each start
+// with the header of the profile, if any.
+func (p Parser) StreamBars(path string, daily bool, write func(year int, bars []model.ZorroT6) error) error {
+	var bars []model.ZorroT6
+	years := yearSplitter{flush: func(year int) error {
+		err := write(year, bars)
+		bars = nil
+		return err
+	}}
+
+	err := eachInput(path, func(r io.Reader) error {
+		b := p.NewBarReader(r, daily)
+		for {
+			t6, parsedTime, err := b.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			year := parsedTime.Year()
+			if daily {
+				year = 0
+			}
+			if err := years.next(year); err != nil {
+				return err
+			}
+			bars = append(bars, t6)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if daily && !years.started {
+		return write(0, nil)
+	}
+	return years.done()
+}
+
+// eachRecord calls fn with every csv record of the file at path, read with
+// comma as delimiter, and its line. Compressed files are read as described for
+// InputSuffixes, lines count from 1 in each csv file of a zip archive. The
+// record is reused between calls.
+func eachRecord(path string, comma rune, fn func(line int, record []string) error) error {
+	return eachInput(path, func(r io.Reader) error {
+		csvReader := csv.NewReader(bufio.NewReader(r))
+		csvReader.Comma = comma
+		csvReader.ReuseRecord = true
+		for line := 1; ; line++ {
+			record, err := csvReader.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return errors.WithMessage(err, "Unable to read records")
+			}
+			if err := fn(line, record); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// A yearSplitter calls flush with the year of the records seen so far whenever
+// the year of consecutive records changes, and once more from done.
+type yearSplitter struct {
+	flush   func(year int) error
+	year    int
+	started bool
+}
+
+func (s *yearSplitter) next(year int) error {
+	if s.started && year != s.year {
+		if err := s.flush(s.year); err != nil {
+			return err
+		}
+	}
+	s.year, s.started = year, true
+	return nil
+}
+
+func (s *yearSplitter) done() error {
+	if !s.started {
+		return nil
+	}
+	return s.flush(s.year)
+}
+
+// StreamTicks reads the tick file at path, see TickRecordToStruct, and calls
+// write with the ticks of each year as soon as the file moves on to the next
+// year, so only a year of ticks is held in memory. A year that reappears in an
+// unsorted file is passed to write again. Of the profile only the delimiter and
+// the zone are used.
+func (p Parser) StreamTicks(path string, write func(year int, ticks []model.ZorroT1) error) error {
+	p, err := p.withProfileZone()
+	if err != nil {
+		return err
+	}
+
+	var ticks []model.ZorroT1
+	years := yearSplitter{flush: func(year int) error {
+		err := write(year, ticks)
+		ticks = nil
+		return err
+	}}
+
+	err = eachRecord(path, p.Profile.Comma(), func(line int, record []string) error {
+		t1, parsedTime, err := p.TickRecordToStruct(record)
+		if err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("Failed to parse tick record %v", line-1))
+		}
+		if err := years.next(parsedTime.Year()); err != nil {
+			return err
+		}
+		ticks = append(ticks, t1)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return years.done()
+}
+
+// StreamOptions reads the options chain at path, a header followed by records
+// as described for OptionRecordToStruct, and calls write with the contracts of
+// each year, see StreamTicks.
+func (p Parser) StreamOptions(path string, write func(year int, contracts []model.ZorroT8) error) error {
+	p, err := p.withProfileZone()
+	if err != nil {
+		return err
+	}
+
+	var contracts []model.ZorroT8
+	years := yearSplitter{flush: func(year int) error {
+		err := write(year, contracts)
+		contracts = nil
+		return err
+	}}
+
+	err = eachRecord(path, p.Profile.Comma(), func(line int, record []string) error {
+		if line == 1 {
+			// skip header line
+			return nil
+		}
+		t8, parsedTime, err := p.OptionRecordToStruct(record)
+		if err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("Failed to parse contract record %v", line-1))
+		}
+		if err := years.next(parsedTime.Year()); err != nil {
+			return err
+		}
+		contracts = append(contracts, t8)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return years.done()
+}
@@ -0,0 +1,82 @@
+package converters
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/dan-lind/t6converter/model"
+	"github.com/pkg/errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func TickRecordToStruct(record []string) (model.ZorroT1, time.Time, error) {
+	return Parser{}.TickRecordToStruct(record)
+}
+
+// TickRecordToStruct converts a tick record of the form date,time,price[,side]
+// from the parser's input to its output zone. Time may carry fractional
+// seconds, e.g. 09:30:01.250, and a side of B or bid marks the price as a bid
+// quote.
+func (p Parser) TickRecordToStruct(record []string) (model.ZorroT1, time.Time, error) {
+	var t1 model.ZorroT1
+	if len(record) < 3 {
+		return model.ZorroT1{}, time.Time{}, errors.Errorf("Expected at least 3 fields, got %v", len(record))
+	}
+
+	parsedTime, err := p.Zones.Parse("2006010215:04:05", record[0]+record[1])
+	if err != nil {
+		return model.ZorroT1{}, time.Time{}, err
+	}
+
+	price, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 32)
+	if err != nil {
+		return model.ZorroT1{}, time.Time{}, err
+	}
+
+	t1.Date = ConvertToOle(parsedTime)
+	t1.Price = float32(price)
+	if len(record) > 3 {
+		switch strings.ToLower(strings.TrimSpace(record[3])) {
+		case "b", "bid":
+			t1.Price = -t1.Price
+		}
+	}
+
+	return t1, parsedTime, nil
+}
+
+// WriteT1Files writes the ticks of each year newest first to the file called
+// name(year), years sharing a name are written to the same file.
+func WriteT1Files(recordMap map[int][]model.ZorroT1, name func(year int) string) error {
+	files := make(map[string][]model.ZorroT1)
+	for year, records := range recordMap {
+		files[name(year)] = append(files[name(year)], records...)
+	}
+
+	for name, records := range files {
+		sort.Slice(records, func(i, j int) bool {
+			return records[i].Date > records[j].Date
+		})
+
+		err := writeFileAtomic(name, func(w io.Writer) error {
+			return writeAllRecords(records, w)
+		})
+		if err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("Unable to write file with path %v", name))
+		}
+	}
+	return nil
+}
+
+// T1FileToStruct reads the ticks of the t1 file at path.
+func T1FileToStruct(path string) ([]model.ZorroT1, error) {
+	var records []model.ZorroT1
+	err := readAllRecords(path, binary.Size(model.ZorroT1{}), "T1", func(n int) interface{} {
+		records = make([]model.ZorroT1, n)
+		return records
+	})
+	return records, err
+}
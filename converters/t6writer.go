@@ -0,0 +1,58 @@
+package converters
+
+import (
+	"bufio"
+	"encoding/binary"
+	"github.com/dan-lind/t6converter/model"
+	"github.com/pkg/errors"
+	"io"
+	"math"
+)
+
+// T6RecordSize is the size of an encoded ZorroT6 record in bytes.
+const T6RecordSize = 32
+
+// A T6Writer encodes ZorroT6 records to an io.Writer in Zorro's little endian
+// t6 layout. Writes are buffered, call Flush once all records are written.
+type T6Writer struct {
+	w   *bufio.Writer
+	buf [T6RecordSize]byte
+}
+
+func NewT6Writer(w io.Writer) *T6Writer {
+	return &T6Writer{w: bufio.NewWriter(w)}
+}
+
+// WriteRecord encodes a single record.
+func (t *T6Writer) WriteRecord(record model.ZorroT6) error {
+	binary.LittleEndian.PutUint64(t.buf[0:], math.Float64bits(record.Date))
+	binary.LittleEndian.PutUint32(t.buf[8:], math.Float32bits(record.High))
+	binary.LittleEndian.PutUint32(t.buf[12:], math.Float32bits(record.Low))
+	binary.LittleEndian.PutUint32(t.buf[16:], math.Float32bits(record.Open))
+	binary.LittleEndian.PutUint32(t.buf[20:], math.Float32bits(record.Close))
+	binary.LittleEndian.PutUint32(t.buf[24:], math.Float32bits(record.Val))
+	binary.LittleEndian.PutUint32(t.buf[28:], uint32(record.Vol))
+
+	if _, err := t.w.Write(t.buf[:]); err != nil {
+		return errors.WithMessage(err, "Unable to write t6 record")
+	}
+	return nil
+}
+
+// Write encodes records in the order given. Zorro expects them newest first.
+func (t *T6Writer) Write(records []model.ZorroT6) error {
+	for _, record := range records {
+		if err := t.WriteRecord(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes any buffered records to the underlying io.Writer.
+func (t *T6Writer) Flush() error {
+	if err := t.w.Flush(); err != nil {
+		return errors.WithMessage(err, "Unable to write t6 records")
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package converters
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/dan-lind/t6converter/model"
+	"github.com/pkg/errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func OptionRecordToStruct(record []string) (model.ZorroT8, time.Time, error) {
+	return Parser{}.OptionRecordToStruct(record)
+}
+
+// OptionRecordToStruct converts a record of an end-of-day options chain laid
+// out as date,underlying,expiry,type,strike,bid,ask,volume,openinterest, with
+// the date converted from the parser's input to its output zone. Type is C or
+// P, optionally followed by E for european style options, e.g. CE.
+func (p Parser) OptionRecordToStruct(record []string) (model.ZorroT8, time.Time, error) {
+	var t8 model.ZorroT8
+	if len(record) < 9 {
+		return model.ZorroT8{}, time.Time{}, errors.Errorf("Expected 9 fields, got %v", len(record))
+	}
+	// trim into a copy, the record may be reused by the csv reader
+	var fields [9]string
+	for i := range fields {
+		fields[i] = strings.TrimSpace(record[i])
+	}
+
+	parsedTime, err := p.Zones.Parse("20060102", fields[0])
+	if err != nil {
+		return model.ZorroT8{}, time.Time{}, err
+	}
+	t8.Date = ConvertToOle(parsedTime)
+
+	expiry, err := time.Parse("20060102", fields[2])
+	if err != nil {
+		return model.ZorroT8{}, time.Time{}, err
+	}
+	t8.Expiry = int32(expiry.Year()*10000 + int(expiry.Month())*100 + expiry.Day())
+
+	switch strings.ToUpper(fields[3]) {
+	case "C", "CALL":
+		t8.Type = model.Call
+	case "P", "PUT":
+		t8.Type = model.Put
+	case "CE":
+		t8.Type = model.Call | model.European
+	case "PE":
+		t8.Type = model.Put | model.European
+	default:
+		return model.ZorroT8{}, time.Time{}, errors.Errorf("Unknown contract type %v", fields[3])
+	}
+
+	values := make([]float32, 0, 6)
+	for _, field := range []string{fields[1], fields[4], fields[5], fields[6], fields[7], fields[8]} {
+		if field == "" {
+			values = append(values, 0)
+			continue
+		}
+		value, err := strconv.ParseFloat(field, 32)
+		if err != nil {
+			return model.ZorroT8{}, time.Time{}, err
+		}
+		values = append(values, float32(value))
+	}
+	t8.Underlying = values[0]
+	t8.Strike = values[1]
+	t8.Bid = values[2]
+	t8.Ask = values[3]
+	t8.Vol = values[4]
+	t8.Val = values[5]
+
+	return t8, parsedTime, nil
+}
+
+// WriteT8Files writes the contracts of each year newest first to the file
+// called name(year), years sharing a name are written to the same file.
+func WriteT8Files(recordMap map[int][]model.ZorroT8, name func(year int) string) error {
+	files := make(map[string][]model.ZorroT8)
+	for year, records := range recordMap {
+		files[name(year)] = append(files[name(year)], records...)
+	}
+
+	for name, records := range files {
+		sort.SliceStable(records, func(i, j int) bool {
+			return records[i].Date > records[j].Date
+		})
+
+		err := writeFileAtomic(name, func(w io.Writer) error {
+			return writeAllRecords(records, w)
+		})
+		if err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("Unable to write file with path %v", name))
+		}
+	}
+	return nil
+}
+
+// T8FileToStruct reads the contracts of the t8 file at path.
+func T8FileToStruct(path string) ([]model.ZorroT8, error) {
+	var records []model.ZorroT8
+	err := readAllRecords(path, binary.Size(model.ZorroT8{}), "T8", func(n int) interface{} {
+		records = make([]model.ZorroT8, n)
+		return records
+	})
+	return records, err
+}
@@ -0,0 +1,85 @@
+package converters
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/dan-lind/t6converter/model"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+var yearSuffix = regexp.MustCompile(`(?i)_(\d{4})\.t6$`)
+
+// VerifyT6File checks the t6 file at path against Zorro's requirements and
+// returns a description of every problem found. The error is only set if the
+// file could not be read.
+func VerifyT6File(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("Unable to read file with path %v", path))
+	}
+
+	if len(data)%T6RecordSize != 0 {
+		return []string{fmt.Sprintf("size %v is not a multiple of %v bytes", len(data), T6RecordSize)}, nil
+	}
+
+	records := make([]model.ZorroT6, len(data)/T6RecordSize)
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, records); err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("Unable to decode records in file %v", path))
+	}
+
+	year := 0
+	if match := yearSuffix.FindStringSubmatch(filepath.Base(path)); match != nil {
+		year, _ = strconv.Atoi(match[1])
+	}
+
+	return VerifyT6(records, year), nil
+}
+
+// VerifyT6 checks records in file order. Unless year is zero every bar must
+// also fall into that year.
+func VerifyT6(records []model.ZorroT6, year int) []string {
+	var problems []string
+	report := func(i int, format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("record %v: ", i)+fmt.Sprintf(format, args...))
+	}
+
+	for i, record := range records {
+		if math.IsNaN(record.Date) || math.IsInf(record.Date, 0) {
+			report(i, "invalid date %v", record.Date)
+			continue
+		}
+		date := ConvertFromOle(record.Date)
+		if i > 0 && record.Date >= records[i-1].Date {
+			report(i, "%v is not older than the record before it", date)
+		}
+		if year != 0 && date.Year() != year {
+			report(i, "%v is not in %v", date, year)
+		}
+
+		valid := true
+		for _, price := range []float32{record.Open, record.High, record.Low, record.Close} {
+			p := float64(price)
+			if math.IsNaN(p) || math.IsInf(p, 0) || p < 0 {
+				report(i, "invalid price %v at %v", price, date)
+				valid = false
+				break
+			}
+		}
+		if !valid {
+			continue
+		}
+
+		upper := float32(math.Max(float64(record.Open), float64(record.Close)))
+		lower := float32(math.Min(float64(record.Open), float64(record.Close)))
+		if record.High < upper || lower < record.Low {
+			report(i, "high %v, open %v, close %v and low %v are inconsistent at %v", record.High, record.Open, record.Close, record.Low, date)
+		}
+	}
+	return problems
+}
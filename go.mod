@@ -1,6 +1,9 @@
 module github.com/dan-lind/t6converter
 
+go 1.16
+
 require (
 	github.com/pkg/errors v0.8.1
 	github.com/stretchr/testify v1.3.0
+	github.com/ulikunitz/xz v0.5.15
 )
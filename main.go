@@ -1,149 +1,192 @@
 package main
 
 import (
-	"errors"
+	"context"
 	"flag"
 	"fmt"
 	c "github.com/dan-lind/t6converter/converters"
-	"github.com/dan-lind/t6converter/model"
+	"github.com/dan-lind/t6converter/t6converter"
 	"log"
 	"os"
-	"path/filepath"
-	"strings"
-	"sync"
+	"os/signal"
+	"regexp"
+	"runtime"
+	"syscall"
 	"time"
+	_ "time/tzdata" // zones for -tz-in and -tz-out on systems without a zone database
 )
 
-// A result is the product of reading and summing a file using MD5.
-type result struct {
-	data map[int][]model.ZorroT6
-	path string
-	err  error
-}
-
-//Data, Time, Open, High, Low, Close, Volume ?
-
 func main() {
 
 	var inputDir = flag.String("in", "", "absolute path to input directory")
 	var outputDir = flag.String("out", "", "absolute path to output directory")
 	var daily = flag.Bool("daily", false, "true if daily resolution")
+	var mode = flag.String("mode", "csvtot6", "conversion mode, csvtot6, csvtot1, csvtot8, bi5tot1, bi5tot6, t6tocsv, inspect or verify")
+	var resample = flag.String("resample", "", "period to aggregate bars into, e.g. 5m, 1h or 1d")
+	var gaps = flag.Bool("gaps", false, "write json and text reports of missing, duplicate and out of order bars")
+	var tzIn = flag.String("tz-in", "", "IANA time zone of the input timestamps, e.g. America/New_York, defaults to the profile's zone or UTC")
+	var tzOut = flag.String("tz-out", "UTC", "IANA time zone of the written timestamps, Zorro expects UTC")
+	var profile = flag.String("profile", "", "input layout, pitrading, pitrading-daily, histdata, mt5, mt5-daily or path to a json profile")
+	var columns = flag.String("columns", "", "input columns numbered from 1, e.g. date=1,time=2,open=3,high=4,low=5,close=6,volume=7,spread=0, or bidopen, bidhigh, bidlow and bidclose next to ask prices")
+	var dateLayout = flag.String("date-layout", "", "Go time layout of the date and time columns, e.g. 2006010215:04")
+	var delimiter = flag.String("delimiter", "", "input field delimiter, a single character or tab")
+	var barPeriod = flag.String("period", "", "bar period of the input, e.g. 5m, checked by -gaps and used to stamp bars at their close, defaults to the profile's or for -gaps to the step between the first bars")
+	var adjust = flag.String("adjust", "", "path to a symbol,date,split,dividend csv file to back-adjust prices with")
+	var bids = flag.Bool("bids", false, "bi5tot1 also writes bid quotes, which Zorro reads as negative prices")
+	var merge = flag.String("merge", "", "merge into existing t6 files, keep-old or keep-new decides conflicting bars")
+	var reportPath = flag.String("report", "", "path to write a json report of converted and failed files to")
+	var workers = flag.Int("workers", runtime.GOMAXPROCS(0), "number of files to read and convert concurrently")
+	var writers = flag.Int("writers", 0, "number of files to write concurrently, defaults to -workers")
+	var nameTemplate = flag.String("name-template", "", "output file names relative to -out, e.g. {symbol}_{year}.t6, {symbol}.t6 or {dir}/{symbol}_{year}.t6")
+	var flat = flag.Bool("flat", false, "write all files directly into -out instead of mirroring the directories below -in")
+	var symbolPattern = flag.String("symbol-pattern", "", "regular expression picking the symbol from input file names by its first group, e.g. ^([A-Z]+)_")
 	flag.Parse()
 
-	start := time.Now()
-
-	processFiles(*inputDir, *outputDir, *daily)
-
-	fmt.Println("All done!")
-	elapsed := time.Since(start)
-	log.Printf("Conversion took %s", elapsed)
-}
-
-// digester reads path names from paths and sends digests of the corresponding
-// files on c until either paths or done is closed.
-func digester(done <-chan struct{}, paths <-chan string, res chan<- result, daily bool) {
-	for path := range paths { // HLpaths
-		data, err := c.FileToCsv(path)
+	opts := t6converter.Options{InputDir: *inputDir, OutputDir: *outputDir, Mode: t6converter.Mode(*mode), Daily: *daily, Gaps: *gaps, Bids: *bids, Workers: *workers, Writers: *writers, Flat: *flat}
+	if *resample != "" {
+		period, err := c.ParsePeriod(*resample)
 		if err != nil {
-			select {
-			case res <- result{nil, path,err}:
-			case <-done:
-				return
-			}
+			log.Fatal(err)
+		}
+		opts.Resample = period
+	}
+	if p, err := loadProfile(*profile, *columns, *dateLayout, *delimiter, *barPeriod, *daily); err != nil {
+		log.Fatal(err)
+	} else {
+		opts.Profile = p
+	}
+	if *nameTemplate != "" {
+		template, err := c.ParseNameTemplate(*nameTemplate)
+		if err != nil {
+			log.Fatal(err)
 		}
+		opts.NameTemplate = template
+	}
+	if *symbolPattern != "" {
+		pattern, err := regexp.Compile(*symbolPattern)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.SymbolPattern = pattern
+	}
+	if policy, err := c.ParseMergePolicy(*merge); err != nil {
+		log.Fatal(err)
+	} else {
+		opts.Merge = policy
+	}
+	if *adjust != "" {
+		actions, err := c.ReadCorporateActions(*adjust)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.Actions = actions
+	}
+	if *tzIn != "" {
+		loc, err := time.LoadLocation(*tzIn)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.Zones.In = loc
+	}
+	if loc, err := time.LoadLocation(*tzOut); err != nil {
+		log.Fatal(err)
+	} else {
+		opts.Zones.Out = loc
+	}
 
-		var records map[int][]model.ZorroT6
-		var pErr error
+	// Stop converting on the first SIGINT or SIGTERM, a second one kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
 
-		if daily {
-			records, pErr = c.RwDailyToStruct(data)
-		} else {
-			records, pErr = c.Rw1minToStruct(data)
+	if *mode == "inspect" {
+		if err := t6converter.Inspect(ctx, *inputDir, os.Stdout); err != nil {
+			log.Fatal(err)
 		}
+		return
+	}
 
-		select {
-		case res <- result{records, path, pErr}:
-		case <-done:
-			return
+	if *mode == "verify" {
+		passed, err := t6converter.Verify(ctx, *inputDir, os.Stdout)
+		if err != nil {
+			log.Fatal(err)
 		}
-
+		if !passed {
+			os.Exit(1)
+		}
+		return
 	}
-}
-
-func processFiles(inputDir string, outputDir string, daily bool) {
 
-	done := make(chan struct{})
-	defer close(done)
+	start := time.Now()
 
-	paths, errc := walkFiles(done, inputDir)
+	rep, err := t6converter.Convert(ctx, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Start a fixed number of goroutines to read and digest files.
-	res := make(chan result) // HLc
-	var wg sync.WaitGroup
-	const numDigesters = 8
-	wg.Add(numDigesters)
-	for i := 0; i < numDigesters; i++ {
-		go func() {
-			digester(done, paths, res, daily) // HLc
-			wg.Done()
-		}()
+	if *reportPath != "" {
+		if err := rep.WriteJSONFile(*reportPath); err != nil {
+			log.Println(err)
+		}
 	}
-	go func() {
-		wg.Wait()
-		close(res) // HLc
-	}()
-	// End of pipeline. OMIT
 
-	var wg2 sync.WaitGroup
+	elapsed := time.Since(start)
+	log.Printf("Conversion took %s", elapsed)
 
-	for r := range res {
-		if r.err != nil {
-			fmt.Println(r.err)
-			return
+	if rep.Canceled {
+		fmt.Fprintf(os.Stderr, "Interrupted, converted %v files before stopping\n", rep.Converted)
+		for _, file := range rep.Files {
+			if file.Error == "" && !file.Skipped {
+				fmt.Fprintln(os.Stderr, file.Path)
+			}
 		}
-		wg2.Add(1)
-		go func(input result) {
-			c.StructToT6File(input.data, outputDir, strings.Split(input.path, ".")[0], daily)
-			wg2.Done()
-		}(r)
 	}
-
-	wg2.Wait()
-	// Check whether the Walk failed.
-	if err := <-errc; err != nil { // HLerrc
-		fmt.Println(err)
+	if rep.HasFailures() {
+		fmt.Fprintf(os.Stderr, "%v of %v files failed\n", rep.Failed, rep.Failed+rep.Converted)
+		rep.Failures().WriteJSON(os.Stderr)
 	}
-
+	switch {
+	case rep.Canceled:
+		// the run is incomplete whether or not files failed
+		os.Exit(130)
+	case rep.HasFailures():
+		os.Exit(1)
+	}
+	fmt.Printf("All done! Converted %v files\n", rep.Converted)
 }
 
-// walkFiles starts a goroutine to walk the directory tree at root and send the
-// path of each regular file on the string channel.  It sends the result of the
-// walk on the error channel.  If done is closed, walkFiles abandons its work.
-func walkFiles(done <-chan struct{}, root string) (<-chan string, <-chan error) {
-	paths := make(chan string)
-	errc := make(chan error, 1)
-	go func() { // HL
-		// Close the paths channel after Walk returns.
-		defer close(paths) // HL
-		// No select needed for this send, since errc is buffered.
-		errc <- filepath.Walk(root, func(path string, info os.FileInfo, err error) error { // HL
-			if err != nil {
-				return err
-			}
-			if !info.Mode().IsRegular() {
-				return nil
-			}
-			if !strings.HasSuffix(strings.ToLower(info.Name()),"txt") && !strings.HasSuffix(strings.ToLower(info.Name()),"csv") {
-				return nil
-			}
-
-			select {
-			case paths <- path: // HL
-			case <-done: // HL
-				return errors.New("walk canceled")
-			}
-			return nil
-		})
-	}()
-	return paths, errc
+// loadProfile returns the named input profile, or the Pitrading layout for the
+// resolution, with the given overrides applied.
+func loadProfile(name string, columns string, dateLayout string, delimiter string, period string, daily bool) (c.Profile, error) {
+	profile := c.Pitrading1min
+	if daily {
+		profile = c.PitradingDaily
+	}
+	if name != "" {
+		p, err := c.LoadProfile(name)
+		if err != nil {
+			return c.Profile{}, err
+		}
+		profile = p
+	}
+	if columns != "" {
+		p, err := profile.WithColumns(columns)
+		if err != nil {
+			return c.Profile{}, err
+		}
+		profile = p
+	}
+	if dateLayout != "" {
+		profile.DateLayout = dateLayout
+	}
+	if delimiter != "" {
+		profile.Delimiter = delimiter
+	}
+	if period != "" {
+		profile.Period = period
+	}
+	return profile, profile.Validate()
 }
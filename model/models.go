@@ -8,4 +8,31 @@ type ZorroT6 struct {
 	Close float32
 	Val   float32
 	Vol   int32
-}
\ No newline at end of file
+}
+
+type ZorroT1 struct {
+	Date  float64
+	Price float32 // negative for bid quotes
+}
+
+// ZorroT8 is Zorro's CONTRACT struct used for option and future chain history
+type ZorroT8 struct {
+	Date       float64
+	Ask        float32
+	Bid        float32
+	Val        float32 // open interest
+	Vol        float32
+	Underlying float32
+	Strike     float32
+	Expiry     int32 // YYYYMMDD
+	Type       int32 // combination of Call, Put, European, Binary and Future
+}
+
+// Contract type flags as defined in Zorro's trading.h
+const (
+	Call     int32 = 1 << 0
+	Put      int32 = 1 << 1
+	European int32 = 1 << 2
+	Binary   int32 = 1 << 3
+	Future   int32 = 1 << 4
+)
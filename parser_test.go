@@ -1,16 +1,27 @@
 package main
 
 import (
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
 	"encoding/binary"
+	"fmt"
 	c "github.com/dan-lind/t6converter/converters"
 	"github.com/dan-lind/t6converter/model"
+	"github.com/dan-lind/t6converter/t6converter"
 	"github.com/stretchr/testify/assert"
+	"github.com/ulikunitz/xz/lzma"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -18,7 +29,7 @@ import (
 func BenchmarkParseCsvToT6(t *testing.B) {
 
 	for i := 0; i < t.N; i++ {
-		processFiles( "test/", "test/", false)
+		t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/", OutputDir: "test/"})
 	}
 
 	t.StopTimer()
@@ -41,6 +52,13 @@ func BenchmarkParse1minToStruct(t *testing.B) {
 	}
 }
 
+func BenchmarkFileToStruct(t *testing.B) {
+
+	for i := 0; i < t.N; i++ {
+		c.Parser{}.FileToStruct("test/perf.csv", false)
+	}
+}
+
 func BenchmarkFileToCsv(t *testing.B) {
 
 	for i := 0; i < t.N; i++ {
@@ -75,7 +93,7 @@ func BenchmarkConvertRecord(t *testing.B) {
 }
 
 func TestProcessFiles(t *testing.T) {
-	processFiles("test/", "test/", false)
+	t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/", OutputDir: "test/"})
 
 	newFiles, _ := ioutil.ReadDir("test/")
 	for _, newFile := range newFiles {
@@ -87,6 +105,485 @@ func TestProcessFiles(t *testing.T) {
 
 }
 
+func TestProcessFilesCollectsErrors(t *testing.T) {
+	os.MkdirAll("test/errors", 0755)
+	defer os.RemoveAll("test/errors")
+	ioutil.WriteFile("test/errors/good.csv", []byte(data1min), 0644)
+	ioutil.WriteFile("test/errors/bad.csv", []byte("2014-01-02,09:30,1,1,1,1,1\n"), 0644)
+
+	rep, err := t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/errors", OutputDir: "test/errors", Workers: 1, Writers: 1})
+	assert.Nil(t, err)
+	assert.True(t, rep.HasFailures())
+	assert.Equal(t, 1, rep.Converted)
+	assert.Equal(t, 1, rep.Failed)
+	assert.Equal(t, 1, len(rep.Failures().Files))
+	assert.Equal(t, "test/errors/bad.csv", rep.Failures().Files[0].Path)
+
+	_, err = os.Stat("test/errors/good_2014.t6")
+	assert.Nil(t, err)
+
+	_, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/errors", OutputDir: "test/errors", Mode: "csvtot7"})
+	assert.NotNil(t, err)
+}
+
+func TestConvertCompressed(t *testing.T) {
+	os.MkdirAll("test/compressed", 0755)
+	defer os.RemoveAll("test/compressed")
+
+	gzfile, _ := os.Create("test/compressed/gz.csv.gz")
+	gz := gzip.NewWriter(gzfile)
+	gz.Write([]byte(data1min))
+	gz.Close()
+	gzfile.Close()
+
+	zipfile, _ := os.Create("test/compressed/zipped.zip")
+	archive := zip.NewWriter(zipfile)
+	jan, _ := archive.Create("zipped_2014.csv")
+	jan.Write([]byte(data1min[:strings.Index(data1min, "20150102")]))
+	feb, _ := archive.Create("zipped_2015.csv")
+	feb.Write([]byte(data1min[strings.Index(data1min, "20150102"):]))
+	status, _ := archive.Create("zipped_2015.txt") // HistData's status report
+	status.Write([]byte("HistData.com (c) 2015\nGap of 120s found between 20150102 09:40 and 20150102 09:42\n"))
+	archive.Create("readme.md")
+	archive.Close()
+	zipfile.Close()
+
+	// bzip2 of the first two lines of data1min, the standard library can't compress bzip2
+	bz2, _ := base64.StdEncoding.DecodeString("QlpoOTFBWSZTWX4wFjIAABZYAAAQAAV/8CAASEqaZNogBVQAGiqQcGYqtOBfEIQpsTmHxZHhgIkbTxQyNQMPPi7kinChIPxgLGQ=")
+	ioutil.WriteFile("test/compressed/bz.csv.bz2", bz2, 0644)
+
+	rep, err := t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/compressed", OutputDir: "test/compressed"})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	assert.Equal(t, 3, rep.Converted)
+
+	assert.Equal(t, 18, len(readt6("test/compressed/gz_2014.t6")))
+	assert.Equal(t, 18, len(readt6("test/compressed/zipped_2014.t6")))
+	assert.Equal(t, 2, len(readt6("test/compressed/zipped_2015.t6")))
+	assert.Equal(t, 2, len(readt6("test/compressed/bz_2014.t6")))
+
+	records, err := c.FileToCsv("test/compressed/zipped.zip")
+	assert.Nil(t, err)
+	assert.Equal(t, 20, len(records))
+
+	// a custom reader is handed the decompressed data as well
+	rep, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/compressed", OutputDir: "test/compressed/reader", Reader: t6converter.CSVReader{}})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	assert.Equal(t, 18, len(readt6("test/compressed/reader/gz_2014.t6")))
+	assert.Equal(t, 18, len(readt6("test/compressed/reader/zipped_2014.t6")))
+	assert.Equal(t, 2, len(readt6("test/compressed/reader/zipped_2015.t6")))
+}
+
+func TestConvertBi5(t *testing.T) {
+	os.MkdirAll("test/dukascopy/EURUSD/2014/00/02", 0755)
+	os.MkdirAll("test/dukascopy/USDJPY/2014/00/02", 0755)
+	defer os.RemoveAll("test/dukascopy")
+
+	// offset in ms, ask and bid in points, ask and bid volume
+	writeBi5("test/dukascopy/EURUSD/2014/00/02/13h_ticks.bi5", [][5]float64{
+		{1500, 137610, 137600, 1.5, 2.25},
+		{30000, 137620, 137605, 1, 1},
+		{61000, 137590, 137580, 0.5, 0.75},
+	})
+	ioutil.WriteFile("test/dukascopy/EURUSD/2014/00/02/14h_ticks.bi5", nil, 0644)
+	writeBi5("test/dukascopy/USDJPY/2014/00/02/09h_ticks.bi5", [][5]float64{
+		{250, 105120, 105110, 1, 1},
+	})
+
+	rep, err := t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/dukascopy", OutputDir: "test/dukascopy", Mode: t6converter.Bi5ToT1})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	assert.Equal(t, 2, rep.Converted)
+
+	data, _ := ioutil.ReadFile("test/dukascopy/EURUSD_2014.t1")
+	assert.Equal(t, 3*12, len(data))
+	t1FromFile := make([]model.ZorroT1, 3)
+	binary.Read(bytes.NewReader(data), binary.LittleEndian, t1FromFile)
+	parsedTime, _ := time.Parse("2006010215:04:05.000", "2014010213:01:01.000")
+	assert.Equal(t, parsedTime, c.ConvertFromOle(t1FromFile[0].Date))
+	assert.Equal(t, float32(1.3759), t1FromFile[0].Price)
+
+	data, _ = ioutil.ReadFile("test/dukascopy/USDJPY_2014.t1")
+	binary.Read(bytes.NewReader(data), binary.LittleEndian, t1FromFile[:1])
+	assert.Equal(t, float32(105.12), t1FromFile[0].Price)
+
+	// bids follow their ask as negative prices
+	rep, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/dukascopy", OutputDir: "test/dukascopy/bids", Mode: t6converter.Bi5ToT1, Bids: true})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	data, _ = ioutil.ReadFile("test/dukascopy/bids/USDJPY_2014.t1")
+	assert.Equal(t, 2*12, len(data))
+	binary.Read(bytes.NewReader(data), binary.LittleEndian, t1FromFile[:2])
+	assert.ElementsMatch(t, []float32{105.12, -105.11}, []float32{t1FromFile[0].Price, t1FromFile[1].Price})
+
+	rep, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/dukascopy", OutputDir: "test/dukascopy", Mode: t6converter.Bi5ToT6})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+
+	t6FromFile := readt6("test/dukascopy/EURUSD_2014.t6")
+	assert.Equal(t, 2, len(t6FromFile))
+	parsedTime, _ = time.Parse("200601021504", "201401021301")
+	assert.Equal(t, c.ConvertToOle(parsedTime), t6FromFile[1].Date)
+	assert.Equal(t, float32(1.3761), t6FromFile[1].Open)
+	assert.Equal(t, float32(1.3762), t6FromFile[1].High)
+	assert.Equal(t, float32(1.3761), t6FromFile[1].Low)
+	assert.Equal(t, float32(1.3762), t6FromFile[1].Close)
+	assert.Equal(t, int32(2), t6FromFile[1].Vol)
+	assert.InDelta(t, 0.000125, t6FromFile[1].Val, 1e-6)
+
+	_, err = c.Bi5Hour("test/dukascopy/EURUSD/13h_ticks.bi5")
+	assert.NotNil(t, err)
+}
+
+func TestConvertBi5Years(t *testing.T) {
+	os.MkdirAll("test/dukascopy-years/EURUSD/2014/11/31", 0755)
+	os.MkdirAll("test/dukascopy-years/EURUSD/2015/00/02", 0755)
+	defer os.RemoveAll("test/dukascopy-years")
+	writeBi5("test/dukascopy-years/EURUSD/2014/11/31/23h_ticks.bi5", [][5]float64{
+		{3530000, 137610, 137600, 1, 1},
+		{3590000, 137620, 137610, 1, 1},
+	})
+	writeBi5("test/dukascopy-years/EURUSD/2015/00/02/08h_ticks.bi5", [][5]float64{
+		{1000, 137590, 137580, 1, 1},
+	})
+
+	// the years of a symbol are a single input, the minute closing on new
+	// year's midnight is written to 2015 without colliding with its ticks
+	rep, err := t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/dukascopy-years", OutputDir: "test/dukascopy-years/out", Mode: t6converter.Bi5ToT6})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	assert.Equal(t, 1, rep.Converted)
+	assert.Equal(t, 1, len(readt6("test/dukascopy-years/out/EURUSD_2014.t6")))
+	bars := readt6("test/dukascopy-years/out/EURUSD_2015.t6")
+	assert.Equal(t, 2, len(bars))
+	newYear, _ := time.Parse("20060102", "20150101")
+	assert.Equal(t, newYear, c.ConvertFromOle(bars[1].Date))
+
+	// a week spanning new year is built from the ticks of both years
+	rep, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/dukascopy-years", OutputDir: "test/dukascopy-years/weekly", Mode: t6converter.Bi5ToT6, Resample: 7 * 24 * time.Hour})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	bars = readt6("test/dukascopy-years/weekly/EURUSD_2015.t6")
+	assert.Equal(t, 1, len(bars))
+	assert.Equal(t, int32(3), bars[0].Vol)
+	assert.Equal(t, float32(1.3761), bars[0].Open)
+	assert.Equal(t, float32(1.3759), bars[0].Close)
+
+	rep, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/dukascopy-years", OutputDir: "test/dukascopy-years/ticks", Mode: t6converter.Bi5ToT1})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	data, _ := ioutil.ReadFile("test/dukascopy-years/ticks/EURUSD_2014.t1")
+	assert.Equal(t, 2*12, len(data))
+	data, _ = ioutil.ReadFile("test/dukascopy-years/ticks/EURUSD_2015.t1")
+	assert.Equal(t, 12, len(data))
+}
+
+func TestTickSpool(t *testing.T) {
+	defer os.Remove("test/spool.t1")
+	start, _ := time.Parse("200601021504", "201401021300")
+
+	// ticks of three days, every day but the last is spooled to a file
+	spool := c.NewTickSpool()
+	for i := 0; i < 6; i++ {
+		tick := model.ZorroT1{Date: c.ConvertToOle(start.Add(time.Duration(i) * 12 * time.Hour)), Price: float32(i)}
+		assert.Nil(t, spool.Add(tick, model.ZorroT1{Date: tick.Date, Price: -tick.Price}))
+	}
+	assert.Nil(t, spool.WriteT1File("test/spool.t1"))
+	assert.Nil(t, spool.Close())
+
+	ticks, err := c.T1FileToStruct("test/spool.t1")
+	assert.Nil(t, err)
+	assert.Equal(t, 12, len(ticks))
+	for i := 1; i < len(ticks); i++ {
+		assert.True(t, ticks[i-1].Date >= ticks[i].Date)
+	}
+	assert.Equal(t, start.Add(60*time.Hour), c.ConvertFromOle(ticks[0].Date))
+	assert.Equal(t, start, c.ConvertFromOle(ticks[11].Date))
+}
+
+// writeBi5 writes ticks of offset, ask, bid, ask volume and bid volume as a
+// Dukascopy bi5 file.
+func writeBi5(path string, ticks [][5]float64) {
+	file, err := os.Create(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	w, err := lzma.NewWriter(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, tick := range ticks {
+		binary.Write(w, binary.BigEndian, []uint32{uint32(tick[0]), uint32(tick[1]), uint32(tick[2])})
+		binary.Write(w, binary.BigEndian, []float32{float32(tick[3]), float32(tick[4])})
+	}
+	if err := w.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func TestTicksToT6(t *testing.T) {
+	tick := func(at string, ask float32) c.DukascopyTick {
+		parsedTime, _ := time.Parse("20060102 15:04:05.000", at)
+		return c.DukascopyTick{Time: parsedTime, Ask: ask, Bid: ask - 0.0001}
+	}
+	ticks := []c.DukascopyTick{
+		tick("20131231 23:59:30.000", 1.3760),
+		tick("20140102 13:00:01.500", 1.3761),
+		tick("20140102 13:00:30.000", 1.3762),
+		tick("20140102 13:01:01.000", 1.3759),
+	}
+
+	t6records := c.TicksToT6(ticks, time.Minute)
+	assert.Equal(t, 0, len(t6records[2013]))
+	assert.Equal(t, 3, len(t6records[2014]))
+	sort.Slice(t6records[2014], func(i, j int) bool {
+		return t6records[2014][i].Date < t6records[2014][j].Date
+	})
+
+	// the first bar closes at new year's midnight
+	parsedTime, _ := time.Parse("200601021504", "201401010000")
+	assert.Equal(t, c.ConvertToOle(parsedTime), t6records[2014][0].Date)
+	parsedTime, _ = time.Parse("200601021504", "201401021301")
+	assert.Equal(t, c.ConvertToOle(parsedTime), t6records[2014][1].Date)
+	assert.Equal(t, float32(1.3761), t6records[2014][1].Open)
+	assert.Equal(t, float32(1.3762), t6records[2014][1].Close)
+	assert.Equal(t, int32(2), t6records[2014][1].Vol)
+}
+
+func TestConvertHst(t *testing.T) {
+	os.MkdirAll("test/hst", 0755)
+	defer os.RemoveAll("test/hst")
+
+	bar := time.Date(2014, 1, 2, 13, 0, 0, 0, time.UTC)
+
+	header := c.HstHeader{Version: 400, Period: 60, Digits: 5}
+	copy(header.Symbol[:], "EURUSD")
+	var v400 bytes.Buffer
+	binary.Write(&v400, binary.LittleEndian, header)
+	binary.Write(&v400, binary.LittleEndian, struct {
+		Time                           int32
+		Open, Low, High, Close, Volume float64
+	}{int32(bar.Unix()), 1.3761, 1.3759, 1.3762, 1.376, 118})
+	ioutil.WriteFile("test/hst/EURUSD60.hst", v400.Bytes(), 0644)
+
+	header.Version = 401
+	header.Symbol = [12]byte{}
+	copy(header.Symbol[:], "GBPUSD")
+	var v401 bytes.Buffer
+	binary.Write(&v401, binary.LittleEndian, header)
+	writeBar401 := func(buf *bytes.Buffer, t time.Time) {
+		binary.Write(buf, binary.LittleEndian, struct {
+			Time                   int64
+			Open, High, Low, Close float64
+			TickVolume             int64
+			Spread                 int32
+			RealVolume             int64
+		}{t.Unix(), 1.3761, 1.3762, 1.3759, 1.376, 118, 12, 0})
+	}
+	for _, t := range []time.Time{bar, bar.AddDate(1, 0, 0)} {
+		writeBar401(&v401, t)
+	}
+	ioutil.WriteFile("test/hst/GBPUSD60.hst", v401.Bytes(), 0644)
+
+	rep, err := t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/hst", OutputDir: "test/hst"})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	assert.Equal(t, 2, rep.Converted)
+
+	for _, name := range []string{"test/hst/EURUSD_2014.t6", "test/hst/GBPUSD_2014.t6"} {
+		t6FromFile := readt6(name)
+		assert.Equal(t, 1, len(t6FromFile))
+		assert.Equal(t, c.ConvertToOle(bar.Add(time.Hour)), t6FromFile[0].Date)
+		assert.Equal(t, float32(1.3761), t6FromFile[0].Open)
+		assert.Equal(t, float32(1.3762), t6FromFile[0].High)
+		assert.Equal(t, float32(1.3759), t6FromFile[0].Low)
+		assert.Equal(t, float32(1.376), t6FromFile[0].Close)
+		assert.Equal(t, int32(118), t6FromFile[0].Vol)
+	}
+	assert.InDelta(t, 0.00012, readt6("test/hst/GBPUSD_2014.t6")[0].Val, 1e-7)
+	assert.Equal(t, 1, len(readt6("test/hst/GBPUSD_2015.t6")))
+
+	// bars are stamped at the close of the hour in server time two hours ahead
+	// of UTC, checked for gaps of the header's period
+	var gapped bytes.Buffer
+	binary.Write(&gapped, binary.LittleEndian, header)
+	writeBar401(&gapped, bar)
+	writeBar401(&gapped, bar.Add(3*time.Hour))
+	gaps := c.NewGapChecker("gapped.hst", time.Minute)
+	_, records, err := c.Parser{Zones: c.Zones{In: time.FixedZone("EET", 2*60*60)}, Gaps: gaps}.ReadHst(&gapped, false)
+	assert.Nil(t, err)
+	assert.Equal(t, c.ConvertToOle(bar.Add(-time.Hour)), records[2014][0].Date)
+	assert.Equal(t, 1, len(gaps.Report().Gaps))
+	assert.Equal(t, 2, gaps.Report().Gaps[0].Missing)
+
+	// truncated bar
+	ioutil.WriteFile("test/hst/GBPUSD60.hst", v401.Bytes()[:v401.Len()-1], 0644)
+	_, err = c.HstFileToStruct("test/hst/GBPUSD60.hst", false)
+	assert.NotNil(t, err)
+}
+
+func TestParseMT5(t *testing.T) {
+	tmpfile := writeTempFile([]byte(dataMT5))
+	defer os.Remove(tmpfile.Name()) // clean up
+
+	profile, err := c.LoadProfile("mt5")
+	assert.Nil(t, err)
+	t6records, err := c.Parser{Profile: profile}.FileToStruct(tmpfile.Name(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(t6records[2014]))
+
+	parsedTime, _ := time.Parse("200601021504", "201401021301")
+	assert.Equal(t, c.ConvertToOle(parsedTime), t6records[2014][0].Date)
+	assert.Equal(t, float32(1.3761), t6records[2014][0].Open)
+	assert.Equal(t, float32(1.3762), t6records[2014][0].High)
+	assert.Equal(t, float32(1.3759), t6records[2014][0].Low)
+	assert.Equal(t, float32(1.376), t6records[2014][0].Close)
+	assert.Equal(t, int32(118), t6records[2014][0].Vol)
+}
+
+func TestNameTemplate(t *testing.T) {
+	os.MkdirAll("test/naming/in/vendor.data", 0755)
+	defer os.RemoveAll("test/naming")
+	ioutil.WriteFile("test/naming/in/vendor.data/EURUSD_1min.csv", []byte(data1min), 0644)
+	ioutil.WriteFile("test/naming/in/vendor.data/GBPUSD-M1.txt", []byte(data1min), 0644)
+
+	rep, err := t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/naming/in", OutputDir: "test/naming/out"})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	assert.Equal(t, 18, len(readt6("test/naming/out/vendor.data/EURUSD_2014.t6")))
+	assert.Equal(t, 2, len(readt6("test/naming/out/vendor.data/GBPUSD_2015.t6")))
+
+	rep, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/naming/in", OutputDir: "test/naming/out", NameTemplate: "{dir}/{symbol}.t6"})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	assert.Equal(t, 20, len(readt6("test/naming/out/vendor.data/EURUSD.t6")))
+
+	pattern := regexp.MustCompile(`^([A-Z]{3})`)
+	rep, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/naming/in", OutputDir: "test/naming/out", NameTemplate: "{symbol}/{year}.t6", SymbolPattern: pattern})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	assert.Equal(t, 2, len(readt6("test/naming/out/GBP/2015.t6")))
+
+	_, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/naming/in", OutputDir: "test/naming/out", NameTemplate: "{symbol}_{month}.t6"})
+	assert.NotNil(t, err)
+	_, err = c.ParseNameTemplate("../{symbol}.t6")
+	assert.NotNil(t, err)
+
+	assert.Equal(t, "AAPL", c.Symbol("data/AAPL.txt"))
+	assert.Equal(t, "EURUSD", c.Symbol("./in/EURUSD_ticks.csv.gz"))
+	assert.Equal(t, "BRK.B", c.Symbol("in/BRK.B_daily.csv"))
+	assert.Equal(t, "1min", c.Symbol("1min.csv"))
+	assert.Equal(t, "EURUSD", c.Symbol("in/DAT_ASCII_EURUSD_M1_2014.csv"))
+	assert.Equal(t, "EURUSD", c.Symbol("in/DAT_MT_EURUSD_M1_201401.csv"))
+	assert.Equal(t, "EURUSD", c.Symbol("in/HISTDATA_COM_ASCII_EURUSD_M1201401.zip"))
+	assert.Equal(t, "2014", c.Symbol("in/2014.csv"))
+	assert.Equal(t, "EURUSD", c.Symbol("in/EURUSD_M1_201401020000_201412312359.csv"))
+	assert.Equal(t, "GBPUSD", c.Symbol("in/GBPUSD_H4_201401020000_201412312000.csv"))
+
+	// yearly files of a symbol are written to its yearly t6 files
+	os.MkdirAll("test/naming/yearly", 0755)
+	ioutil.WriteFile("test/naming/yearly/DAT_ASCII_EURUSD_M1_2013.csv", []byte("20131231,16:00,1,1,1,1,1\n"), 0644)
+	ioutil.WriteFile("test/naming/yearly/DAT_ASCII_EURUSD_M1_2014.csv", []byte("20140102,09:30,2,2,2,2,2\n"), 0644)
+	rep, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/naming/yearly", OutputDir: "test/naming/yearly"})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	assert.Equal(t, 1, len(readt6("test/naming/yearly/EURUSD_2013.t6")))
+	assert.Equal(t, 1, len(readt6("test/naming/yearly/EURUSD_2014.t6")))
+}
+
+func TestMirrorDirectories(t *testing.T) {
+	os.MkdirAll("test/mirror/in/stocks", 0755)
+	os.MkdirAll("test/mirror/in/etfs", 0755)
+	defer os.RemoveAll("test/mirror")
+	ioutil.WriteFile("test/mirror/in/stocks/AAPL.txt", []byte(data1min), 0644)
+	ioutil.WriteFile("test/mirror/in/etfs/AAPL.txt", []byte(data1min), 0644)
+
+	rep, err := t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/mirror/in", OutputDir: "test/mirror/out"})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	assert.Equal(t, 18, len(readt6("test/mirror/out/stocks/AAPL_2014.t6")))
+	assert.Equal(t, 18, len(readt6("test/mirror/out/etfs/AAPL_2014.t6")))
+
+	rep, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/mirror/out", OutputDir: "test/mirror/csv", Mode: t6converter.T6ToCsv})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	_, err = os.Stat("test/mirror/csv/stocks/AAPL_2014.csv")
+	assert.Nil(t, err)
+
+	// flattened, the second AAPL.txt would overwrite the first
+	rep, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/mirror/in", OutputDir: "test/mirror/flat", Flat: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, rep.Converted)
+	assert.Equal(t, 1, rep.Failed)
+	assert.Contains(t, rep.Failures().Files[0].Error, "collides")
+	assert.Equal(t, 18, len(readt6("test/mirror/flat/AAPL_2014.t6")))
+
+	// the first input in walk order wins, the error names both
+	failure := rep.Failures().Files[0]
+	assert.Equal(t, filepath.Join("test/mirror/in/stocks/AAPL.txt"), failure.Path)
+	assert.Contains(t, failure.Error, filepath.Join("test/mirror/in/etfs/AAPL.txt"))
+
+	// merging inputs share their output files
+	rep, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/mirror/in", OutputDir: "test/mirror/merged", Flat: true, Merge: c.KeepNew})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	assert.Equal(t, 2, rep.Converted)
+	assert.Equal(t, 18, len(readt6("test/mirror/merged/AAPL_2014.t6")))
+}
+
+func TestConvertCanceled(t *testing.T) {
+	os.MkdirAll("test/canceled", 0755)
+	defer os.RemoveAll("test/canceled")
+	ioutil.WriteFile("test/canceled/good.csv", []byte(data1min), 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rep, err := t6converter.Convert(ctx, t6converter.Options{InputDir: "test/canceled", OutputDir: "test/canceled"})
+	assert.Nil(t, err)
+	assert.True(t, rep.Canceled)
+	assert.False(t, rep.HasFailures())
+	assert.Equal(t, 0, rep.Converted)
+
+	_, err = os.Stat("test/canceled/good_2014.t6")
+	assert.True(t, os.IsNotExist(err))
+
+	// a file canceled while it is converted is reported as skipped
+	ctx, cancel = context.WithCancel(context.Background())
+	reader := cancelingReader{cancel: cancel}
+	rep, err = t6converter.Convert(ctx, t6converter.Options{InputDir: "test/canceled", OutputDir: "test/canceled", Reader: reader})
+	assert.Nil(t, err)
+	assert.True(t, rep.Canceled)
+	assert.False(t, rep.HasFailures())
+	assert.Equal(t, 1, rep.Skipped)
+	assert.True(t, rep.Files[0].Skipped)
+}
+
+// cancelingReader cancels the run while reading its input.
+type cancelingReader struct {
+	cancel context.CancelFunc
+}
+
+func (r cancelingReader) ReadBars(in io.Reader) (map[int][]model.ZorroT6, error) {
+	r.cancel()
+	return t6converter.CSVReader{}.ReadBars(in)
+}
+
+func TestConvertStream(t *testing.T) {
+	var buf bytes.Buffer
+	err := t6converter.ConvertStream(strings.NewReader(data1min), &buf, t6converter.CSVReader{})
+	assert.Nil(t, err)
+	assert.Equal(t, 20*32, buf.Len())
+
+	t6records := make([]model.ZorroT6, 20)
+	binary.Read(&buf, binary.LittleEndian, t6records)
+	parsedTime, _ := time.Parse("200601021504", "201501020950")
+	assert.Equal(t, parsedTime, c.ConvertFromOle(t6records[0].Date))
+	assert.Equal(t, int32(67004), t6records[19].Vol)
+}
+
 func TestFileToCsv(t *testing.T) {
 	tmpfile := writeTempFile([]byte(data1min))
 	defer os.Remove(tmpfile.Name()) // clean up
@@ -120,7 +617,7 @@ func TestParse1minToStruct(t *testing.T) {
 	assert.Equal(t, 18, len(t6records[2014]))
 	assert.Equal(t, 2, len(t6records[2015]))
 
-	parsedTime, _ := time.Parse("200601021504", "201401020930")
+	parsedTime, _ := time.Parse("200601021504", "201401020931")
 	assert.Equal(t, c.ConvertToOle(parsedTime), t6records[2014][0].Date)
 	assert.Equal(t, float32(38.88), t6records[2014][0].Open)
 	assert.Equal(t, float32(38.88), t6records[2014][0].High)
@@ -128,7 +625,7 @@ func TestParse1minToStruct(t *testing.T) {
 	assert.Equal(t, float32(38.85), t6records[2014][0].Close)
 	assert.Equal(t, int32(67004), t6records[2014][0].Vol)
 
-	parsedTime2, _ := time.Parse("200601021504", "201501020949")
+	parsedTime2, _ := time.Parse("200601021504", "201501020950")
 	assert.Equal(t, c.ConvertToOle(parsedTime2), t6records[2015][1].Date)
 	assert.Equal(t, float32(38.59), t6records[2015][1].Open)
 	assert.Equal(t, float32(38.61), t6records[2015][1].High)
@@ -138,6 +635,61 @@ func TestParse1minToStruct(t *testing.T) {
 
 }
 
+func TestBarReader(t *testing.T) {
+	reader := c.Parser{}.NewBarReader(strings.NewReader(data1min), false)
+
+	t6, parsedTime, err := reader.Read()
+	assert.Nil(t, err)
+	expected, _ := time.Parse("200601021504", "201401020931")
+	assert.Equal(t, expected, parsedTime)
+	assert.Equal(t, float32(38.85), t6.Close)
+
+	rest, err := reader.ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, 17, len(rest[2014]))
+	assert.Equal(t, 2, len(rest[2015]))
+
+	_, _, err = reader.Read()
+	assert.Equal(t, io.EOF, err)
+
+	tmpfile := writeTempFile([]byte(dailyStockData))
+	defer os.Remove(tmpfile.Name()) // clean up
+	records, _ := c.FileToCsv(tmpfile.Name())
+	expectedDaily, _ := c.RwDailyToStruct(records)
+	streamed, err := c.Parser{}.FileToStruct(tmpfile.Name(), true)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedDaily, streamed)
+
+	_, err = c.Parser{}.NewBarReader(strings.NewReader("20140102,9.30,1,1,1,1,1\n"), false).ReadAll()
+	assert.NotNil(t, err)
+}
+
+func TestStreamBars(t *testing.T) {
+	os.MkdirAll("test/stream", 0755)
+	defer os.RemoveAll("test/stream")
+	unsorted := "20131231,16:00,1,1,1,1,1\n20140102,09:30,2,2,2,2,2\n20140102,09:31,3,3,3,3,3\n20131231,15:59,4,4,4,4,4\n"
+	ioutil.WriteFile("test/stream/unsorted.csv", []byte(unsorted), 0644)
+
+	var years []int
+	var sizes []int
+	err := c.Parser{}.StreamBars("test/stream/unsorted.csv", false, func(year int, bars []model.ZorroT6) error {
+		years = append(years, year)
+		sizes = append(sizes, len(bars))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2013, 2014, 2013}, years)
+	assert.Equal(t, []int{1, 2, 1}, sizes)
+
+	// the year the file returns to is merged into the one written before
+	rep, err := t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/stream", OutputDir: "test/stream"})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	bars, err := c.T6FileToStruct("test/stream/unsorted_2013.t6")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(bars))
+}
+
 func TestParseDailyToStruct(t *testing.T) {
 	tmpfile := writeTempFile([]byte(dailyStockData))
 	defer os.Remove(tmpfile.Name()) // clean up
@@ -202,7 +754,7 @@ func TestCreateT6File(t *testing.T) {
 
 	assert.Equal(t, 18, len(t6FromFile))
 
-	parsedTime, _ := time.Parse("200601021504", "201401020947")
+	parsedTime, _ := time.Parse("200601021504", "201401020948")
 
 	assert.Equal(t, parsedTime, c.ConvertFromOle(t6FromFile[0].Date))
 	assert.Equal(t, float32(38.67), t6FromFile[0].Open)
@@ -214,7 +766,7 @@ func TestCreateT6File(t *testing.T) {
 	t6FromFile2 := readt6("test/1min_2015.t6")
 
 	assert.Equal(t, 2, len(t6FromFile2))
-	parsedTime2, _ := time.Parse("200601021504", "201501020949")
+	parsedTime2, _ := time.Parse("200601021504", "201501020950")
 	assert.Equal(t, parsedTime2, c.ConvertFromOle(t6FromFile2[0].Date))
 	assert.Equal(t, float32(38.59), t6FromFile2[0].Open)
 	assert.Equal(t, float32(38.61), t6FromFile2[0].High)
@@ -227,6 +779,669 @@ func TestCreateT6File(t *testing.T) {
 
 }
 
+func TestT6FileToCsv(t *testing.T) {
+	tmpfile := writeTempFile([]byte(data1min))
+	defer os.Remove(tmpfile.Name()) // clean up
+	records, _ := c.FileToCsv(tmpfile.Name())
+	t6records, _ := c.Rw1minToStruct(records)
+
+	c.StructToT6File(t6records, "test/", "1min", false)
+	defer os.Remove("test/1min_2014.t6")
+	defer os.Remove("test/1min_2015.t6")
+
+	t6FromFile, err := c.T6FileToStruct("test/1min_2014.t6")
+	assert.Nil(t, err)
+	assert.Equal(t, readt6("test/1min_2014.t6"), t6FromFile)
+
+	err = c.StructToCsvFile(t6FromFile, "test/1min_2014.csv")
+	assert.Nil(t, err)
+	defer os.Remove("test/1min_2014.csv")
+
+	csvRecords, _ := c.FileToCsv("test/1min_2014.csv")
+	assert.Equal(t, 18, len(csvRecords))
+	assert.Equal(t, []string{"20140102", "09:31", "38.88", "38.88", "38.82", "38.85", "67004"}, csvRecords[0])
+	assert.Equal(t, []string{"20140102", "09:48", "38.67", "38.68", "38.61", "38.61", "7784"}, csvRecords[17])
+
+	_, err = c.T6FileToStruct(tmpfile.Name())
+	assert.NotNil(t, err)
+}
+
+func TestCreateT1File(t *testing.T) {
+	tmpfile := writeTempFile([]byte(dataTicks))
+	defer os.Remove(tmpfile.Name()) // clean up
+
+	t1records := make(map[int][]model.ZorroT1)
+	err := c.Parser{}.StreamTicks(tmpfile.Name(), func(year int, ticks []model.ZorroT1) error {
+		t1records[year] = append(t1records[year], ticks...)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(t1records[2014]))
+	assert.Equal(t, 1, len(t1records[2015]))
+
+	c.WriteT1Files(t1records, func(year int) string { return fmt.Sprintf("test/ticks_%v.t1", year) })
+	defer os.Remove("test/ticks_2014.t1")
+	defer os.Remove("test/ticks_2015.t1")
+
+	data, _ := ioutil.ReadFile("test/ticks_2014.t1")
+	assert.Equal(t, 4*12, len(data))
+
+	t1FromFile := make([]model.ZorroT1, 4)
+	binary.Read(bytes.NewReader(data), binary.LittleEndian, t1FromFile)
+
+	parsedTime, _ := time.Parse("2006010215:04:05.000", "2014010209:30:01.500")
+	assert.Equal(t, parsedTime, c.ConvertFromOle(t1FromFile[0].Date))
+	assert.Equal(t, float32(-38.87), t1FromFile[0].Price)
+	assert.Equal(t, float32(38.88), t1FromFile[1].Price)
+	assert.True(t, t1FromFile[1].Date > t1FromFile[2].Date)
+}
+
+func TestConvertTicksWithDelimiter(t *testing.T) {
+	os.MkdirAll("test/delimiter/ticks", 0755)
+	os.MkdirAll("test/delimiter/options", 0755)
+	defer os.RemoveAll("test/delimiter")
+	ioutil.WriteFile("test/delimiter/ticks/ticks.csv", []byte(strings.Replace(dataTicks, ",", ";", -1)), 0644)
+	ioutil.WriteFile("test/delimiter/options/options.csv", []byte(strings.Replace(dataOptions, ",", "\t", -1)), 0644)
+
+	rep, err := t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/delimiter/ticks", OutputDir: "test/delimiter/ticks", Mode: t6converter.CsvToT1, Profile: c.Profile{Delimiter: ";"}})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	data, _ := ioutil.ReadFile("test/delimiter/ticks/ticks_2014.t1")
+	assert.Equal(t, 4*12, len(data))
+
+	rep, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/delimiter/options", OutputDir: "test/delimiter/options", Mode: t6converter.CsvToT8, Profile: c.Profile{Delimiter: "tab"}})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	data, _ = ioutil.ReadFile("test/delimiter/options/options_2014.t8")
+	assert.Equal(t, 3*40, len(data))
+
+	// ticks and contracts are converted from the input zone as well
+	newYork, _ := time.LoadLocation("America/New_York")
+	rep, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/delimiter/ticks", OutputDir: "test/delimiter/zoned", Mode: t6converter.CsvToT1, Profile: c.Profile{Delimiter: ";"}, Zones: c.Zones{In: newYork}})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	ticks, _ := c.T1FileToStruct("test/delimiter/zoned/ticks_2014.t1")
+	parsedTime, _ := time.Parse("2006010215:04:05.000", "2014010214:30:01.500")
+	assert.Equal(t, parsedTime, c.ConvertFromOle(ticks[0].Date))
+
+	rep, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/delimiter/options", OutputDir: "test/delimiter/zoned", Mode: t6converter.CsvToT8, Profile: c.Profile{Delimiter: "tab", Zone: "America/New_York"}})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	contracts, _ := c.T8FileToStruct("test/delimiter/zoned/options_2014.t8")
+	parsedTime, _ = time.Parse("200601021504", "201401030500")
+	assert.Equal(t, parsedTime, c.ConvertFromOle(contracts[0].Date))
+}
+
+func TestCreateT8File(t *testing.T) {
+	tmpfile := writeTempFile([]byte(dataOptions))
+	defer os.Remove(tmpfile.Name()) // clean up
+
+	t8records := make(map[int][]model.ZorroT8)
+	err := c.Parser{}.StreamOptions(tmpfile.Name(), func(year int, contracts []model.ZorroT8) error {
+		t8records[year] = append(t8records[year], contracts...)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(t8records[2014]))
+	assert.Equal(t, 1, len(t8records[2015]))
+
+	c.WriteT8Files(t8records, func(year int) string { return fmt.Sprintf("test/options_%v.t8", year) })
+	defer os.Remove("test/options_2014.t8")
+	defer os.Remove("test/options_2015.t8")
+
+	data, _ := ioutil.ReadFile("test/options_2014.t8")
+	assert.Equal(t, 3*40, len(data))
+
+	t8FromFile := make([]model.ZorroT8, 3)
+	binary.Read(bytes.NewReader(data), binary.LittleEndian, t8FromFile)
+
+	parsedTime, _ := time.Parse("20060102", "20140103")
+	assert.Equal(t, parsedTime, c.ConvertFromOle(t8FromFile[0].Date))
+	assert.Equal(t, model.Put, t8FromFile[0].Type)
+	assert.Equal(t, int32(20140117), t8FromFile[0].Expiry)
+	assert.Equal(t, float32(180), t8FromFile[0].Strike)
+	assert.Equal(t, float32(183.5), t8FromFile[0].Underlying)
+	assert.Equal(t, float32(1.15), t8FromFile[0].Bid)
+	assert.Equal(t, float32(1.2), t8FromFile[0].Ask)
+	assert.Equal(t, float32(530), t8FromFile[0].Vol)
+	assert.Equal(t, float32(12011), t8FromFile[0].Val)
+	assert.Equal(t, model.Call|model.European, t8FromFile[2].Type)
+
+	// the record is left as read, csv.Reader may reuse it
+	record := []string{"20140102", " 184.1", "20140117", " C ", "185", "1.05", "1.1", "1200", "23410"}
+	_, _, err = c.OptionRecordToStruct(record)
+	assert.Nil(t, err)
+	assert.Equal(t, " C ", record[3])
+}
+
+func TestResample(t *testing.T) {
+	tmpfile := writeTempFile([]byte(data1min))
+	defer os.Remove(tmpfile.Name()) // clean up
+	records, _ := c.FileToCsv(tmpfile.Name())
+	t6records, _ := c.Rw1minToStruct(records)
+
+	period, err := c.ParsePeriod("5m")
+	assert.Nil(t, err)
+	bars := c.Resample(t6records[2014], period)
+	assert.Equal(t, 4, len(bars))
+
+	// bars are stamped with their close time, the minutes closing 09:31 to
+	// 09:35 make up the bar of 09:35
+	parsedTime, _ := time.Parse("200601021504", "201401020935")
+	assert.Equal(t, parsedTime, c.ConvertFromOle(bars[0].Date))
+	assert.Equal(t, float32(38.88), bars[0].Open)
+	assert.Equal(t, float32(38.88), bars[0].High)
+	assert.Equal(t, float32(38.78), bars[0].Low)
+	assert.Equal(t, float32(38.81), bars[0].Close)
+	assert.Equal(t, int32(67004+2805+3380+12083+1320), bars[0].Vol)
+
+	parsedTime2, _ := time.Parse("200601021504", "201401020950")
+	assert.Equal(t, parsedTime2, c.ConvertFromOle(bars[3].Date))
+	assert.Equal(t, float32(38.59), bars[3].Open)
+	assert.Equal(t, float32(38.61), bars[3].Close)
+
+	period, err = c.ParsePeriod("1d")
+	assert.Nil(t, err)
+	daily := c.Resample(t6records[2014], period)
+	assert.Equal(t, 1, len(daily))
+	parsedTime3, _ := time.Parse("20060102", "20140103")
+	assert.Equal(t, parsedTime3, c.ConvertFromOle(daily[0].Date))
+
+	// the hour closing at midnight on new year's eve belongs to the next year
+	lastHour, _ := time.Parse("200601021504", "201412312330")
+	years := c.ResampleYears(map[int][]model.ZorroT6{2014: {{Date: c.ConvertToOle(lastHour), Open: 1, High: 1, Low: 1, Close: 1}}}, time.Hour, false)
+	assert.Empty(t, years[2014])
+	assert.Equal(t, 1, len(years[2015]))
+
+	// a bar closing on the hour ends that hour's bar
+	onTheHour, _ := time.Parse("200601021504", "201401021000")
+	hourly := c.Resample([]model.ZorroT6{{Date: c.ConvertToOle(onTheHour)}, {Date: c.ConvertToOle(onTheHour.Add(time.Minute))}}, time.Hour)
+	assert.Equal(t, 2, len(hourly))
+	assert.Equal(t, onTheHour, c.ConvertFromOle(hourly[0].Date))
+
+	_, err = c.ParsePeriod("xd")
+	assert.NotNil(t, err)
+}
+
+func TestConvertResampled(t *testing.T) {
+	os.MkdirAll("test/resampled", 0755)
+	defer os.RemoveAll("test/resampled")
+
+	// the week of monday 2014-12-29 closes in 2015 and is built from both years
+	ioutil.WriteFile("test/resampled/SPY.csv", []byte("20141230,09:30,10,12,9,11,100\n20150102,09:30,11,14,10,13,200\n20150105,09:30,13,13,12,12,300\n"), 0644)
+
+	rep, err := t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/resampled", OutputDir: "test/resampled", Resample: 7 * 24 * time.Hour})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+
+	_, err = os.Stat("test/resampled/SPY_2014.t6")
+	assert.True(t, os.IsNotExist(err))
+	bars := readt6("test/resampled/SPY_2015.t6")
+	assert.Equal(t, 2, len(bars))
+	week, _ := time.Parse("20060102", "20150105")
+	assert.Equal(t, week, c.ConvertFromOle(bars[1].Date))
+	assert.Equal(t, float32(10), bars[1].Open)
+	assert.Equal(t, float32(14), bars[1].High)
+	assert.Equal(t, float32(9), bars[1].Low)
+	assert.Equal(t, float32(13), bars[1].Close)
+	assert.Equal(t, int32(300), bars[1].Vol)
+	assert.Equal(t, int32(300), bars[0].Vol)
+}
+
+func TestConvertYearlyFiles(t *testing.T) {
+	os.MkdirAll("test/yearly/in", 0755)
+	defer os.RemoveAll("test/yearly")
+	ioutil.WriteFile("test/yearly/in/SPY_2014.csv", []byte("20141231,09:30,10,12,9,11,50\n20141231,09:31,11,11,10,11,50\n20141231,09:34,11,11,10,10,100\n"), 0644)
+	ioutil.WriteFile("test/yearly/in/SPY_2015.csv", []byte("20150102,09:30,11,14,10,13,100\n20150102,09:31,13,13,12,13,100\n20150102,09:34,13,13,12,12,200\n"), 0644)
+
+	// gap reports are named after their input
+	rep, err := t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/yearly/in", OutputDir: "test/yearly/gaps", Gaps: true})
+	assert.Nil(t, err)
+	assert.False(t, rep.HasFailures())
+	for _, name := range []string{"test/yearly/gaps/SPY_2014_gaps.json", "test/yearly/gaps/SPY_2015_gaps.json"} {
+		_, err = os.Stat(name)
+		assert.Nil(t, err)
+	}
+
+	// the last day of 2014 closes on new year's midnight, it is merged into
+	// the bars of 2015 rather than colliding with them
+	for i := 0; i < 2; i++ {
+		rep, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/yearly/in", OutputDir: "test/yearly/daily", Resample: 24 * time.Hour})
+		assert.Nil(t, err)
+		assert.False(t, rep.HasFailures())
+		bars := readt6("test/yearly/daily/SPY_2015.t6")
+		assert.Equal(t, 2, len(bars))
+		newYear, _ := time.Parse("20060102", "20150101")
+		assert.Equal(t, newYear, c.ConvertFromOle(bars[1].Date))
+		assert.Equal(t, int32(200), bars[1].Vol)
+	}
+
+	// a week spanning new year is combined from both files, also when run again
+	for i := 0; i < 2; i++ {
+		rep, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/yearly/in", OutputDir: "test/yearly/weekly", Resample: 7 * 24 * time.Hour})
+		assert.Nil(t, err)
+		assert.False(t, rep.HasFailures())
+		bars := readt6("test/yearly/weekly/SPY_2015.t6")
+		assert.Equal(t, 1, len(bars))
+		assert.Equal(t, float32(10), bars[0].Open)
+		assert.Equal(t, float32(14), bars[0].High)
+		assert.Equal(t, float32(9), bars[0].Low)
+		assert.Equal(t, float32(12), bars[0].Close)
+		assert.Equal(t, int32(600), bars[0].Vol)
+	}
+
+	// bars of their own in the same year still collide
+	ioutil.WriteFile("test/yearly/in/SPY_2015.txt", []byte("20150105,09:30,1,1,1,1,1\n"), 0644)
+	rep, err = t6converter.Convert(context.Background(), t6converter.Options{InputDir: "test/yearly/in", OutputDir: "test/yearly/copy"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, rep.Failed)
+	assert.Contains(t, rep.Failures().Files[0].Error, "collides")
+}
+
+func TestGapReport(t *testing.T) {
+	tmpfile := writeTempFile([]byte(dataGaps))
+	defer os.Remove(tmpfile.Name()) // clean up
+	records, _ := c.FileToCsv(tmpfile.Name())
+
+	gaps := c.NewGapChecker("gaps", time.Minute)
+	_, err := c.Parser{Gaps: gaps}.Rw1minToStruct(records)
+	assert.Nil(t, err)
+
+	report := gaps.Report()
+	assert.True(t, report.HasIssues())
+	assert.Equal(t, 7, report.Bars)
+	assert.Equal(t, 1, len(report.Gaps))
+	assert.Equal(t, 2, report.Gaps[0].Missing)
+	assert.Equal(t, 1, len(report.Duplicates))
+	assert.Equal(t, 4, report.Duplicates[0].Line)
+	assert.Equal(t, 1, len(report.OutOfOrder))
+	assert.Equal(t, 5, report.OutOfOrder[0].Line)
+
+	err = c.WriteGapReportFile(report, "test/gaps_gaps")
+	assert.Nil(t, err)
+	defer os.Remove("test/gaps_gaps.json")
+	defer os.Remove("test/gaps_gaps.txt")
+
+	text, _ := ioutil.ReadFile("test/gaps_gaps.txt")
+	assert.Contains(t, string(text), "gap 2014-01-02 09:32 -> 2014-01-02 09:35, 2 missing bars")
+
+	dailyfile := writeTempFile([]byte(dailyStockData))
+	defer os.Remove(dailyfile.Name()) // clean up
+	dailyRecords, _ := c.FileToCsv(dailyfile.Name())
+
+	daily := c.NewGapChecker("daily", 24*time.Hour)
+	c.Parser{Gaps: daily}.RwDailyToStruct(dailyRecords)
+	// weekends are skipped, only friday 20010601 is missing
+	assert.Equal(t, 1, len(daily.Report().Gaps))
+	assert.Equal(t, 1, daily.Report().Gaps[0].Missing)
+
+	// without a period it is inferred from the first bars, 5-minute bars have
+	// no gaps between them and a skipped bar is a single missing one
+	var fiveMin [][]string
+	for i, minute := range []int{0, 5, 10, 15, 20, 25, 35, 40, 45, 50, 55} {
+		fiveMin = append(fiveMin, []string{"20140102", fmt.Sprintf("10:%02d", minute), "1", "1", "1", "1", strconv.Itoa(i)})
+	}
+	inferred := c.NewGapChecker("5min", 0)
+	c.Parser{Gaps: inferred}.Rw1minToStruct(fiveMin)
+	assert.Equal(t, 11, inferred.Report().Bars)
+	assert.Equal(t, 1, len(inferred.Report().Gaps))
+	assert.Equal(t, 1, inferred.Report().Gaps[0].Missing)
+
+	short := c.NewGapChecker("short", 0)
+	c.Parser{Gaps: short}.Rw1minToStruct(fiveMin[5:8])
+	assert.Equal(t, 3, short.Report().Bars)
+	assert.Equal(t, 1, len(short.Report().Gaps))
+
+	// or taken from the profile
+	profile := c.Pitrading1min
+	profile.Period = "5m"
+	assert.Nil(t, profile.Validate())
+	period, _ := profile.BarPeriod()
+	assert.Equal(t, 5*time.Minute, period)
+	profile.Period = "5x"
+	assert.NotNil(t, profile.Validate())
+}
+
+func TestParseInTimeZone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	assert.Nil(t, err)
+	parser := c.Parser{Zones: c.Zones{In: newYork}}
+
+	// EST in winter
+	t6, parsedTime, err := parser.RecordToStruct([]string{"20140102", "09:30", "38.88", "38.88", "38.82", "38.85", "67004"})
+	assert.Nil(t, err)
+	expected, _ := time.Parse("200601021504", "201401021430")
+	assert.Equal(t, expected, parsedTime)
+	assert.Equal(t, c.ConvertToOle(expected), t6.Date)
+
+	// EDT in summer
+	_, parsedTime, _ = parser.RecordToStruct([]string{"20140702", "09:30", "38.88", "38.88", "38.82", "38.85", "67004"})
+	expected, _ = time.Parse("200601021504", "201407021330")
+	assert.Equal(t, expected, parsedTime)
+
+	// 2014-03-09 02:30 does not exist in New York, 01:59 EST is followed by 03:00 EDT
+	_, before, _ := parser.RecordToStruct([]string{"20140309", "01:59", "1", "1", "1", "1", "1"})
+	_, after, _ := parser.RecordToStruct([]string{"20140309", "03:00", "1", "1", "1", "1", "1"})
+	assert.Equal(t, time.Minute, after.Sub(before))
+
+	// converting to another zone keeps wall clock time of that zone
+	london, _ := time.LoadLocation("Europe/London")
+	_, parsedTime, _ = c.Parser{Zones: c.Zones{In: newYork, Out: london}}.RecordToStruct([]string{"20140702", "09:30", "1", "1", "1", "1", "1"})
+	expected, _ = time.Parse("200601021504", "201407021430")
+	assert.Equal(t, expected, parsedTime)
+}
+
+func TestParseWithProfile(t *testing.T) {
+	tmpfile := writeTempFile([]byte(dataSemicolon))
+	defer os.Remove(tmpfile.Name()) // clean up
+
+	profile, err := c.Profile{Delimiter: ";", Header: true, DateLayout: "02.01.2006 15:04:05"}.WithColumns("date=2,time=0,open=3,high=4,low=5,close=6,volume=0,spread=7")
+	assert.Nil(t, err)
+
+	records, err := c.ReadCsv(tmpfile.Name(), profile.Comma())
+	assert.Nil(t, err)
+	t6records, err := c.Parser{Profile: profile}.Rw1minToStruct(records)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(t6records[2014]))
+
+	parsedTime, _ := time.Parse("200601021504", "201401020931")
+	assert.Equal(t, c.ConvertToOle(parsedTime), t6records[2014][1].Date)
+	assert.Equal(t, float32(1.3671), t6records[2014][1].Open)
+	assert.Equal(t, float32(1.3675), t6records[2014][1].High)
+	assert.Equal(t, float32(1.3668), t6records[2014][1].Low)
+	assert.Equal(t, float32(1.3670), t6records[2014][1].Close)
+	assert.Equal(t, float32(0.0002), t6records[2014][1].Val)
+	assert.Equal(t, int32(0), t6records[2014][1].Vol)
+
+	_, err = profile.WithColumns("date=1,bid=2")
+	assert.NotNil(t, err)
+	_, err = profile.WithColumns("open=0")
+	assert.NotNil(t, err)
+
+	jsonfile := writeTempFile([]byte(`{"delimiter": ";", "header": true, "dateLayout": "02.01.2006 15:04:05", "date": 2, "open": 3, "high": 4, "low": 5, "close": 6, "spread": 7}`))
+	defer os.Remove(jsonfile.Name()) // clean up
+	fromJson, err := c.LoadProfile(jsonfile.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, profile, fromJson)
+
+	daily, err := c.LoadProfile("pitrading-daily")
+	assert.Nil(t, err)
+	assert.Equal(t, c.PitradingDaily, daily)
+}
+
+func TestParseHistData(t *testing.T) {
+	tmpfile := writeTempFile([]byte(dataHistData))
+	defer os.Remove(tmpfile.Name()) // clean up
+
+	profile, err := c.LoadProfile("histdata")
+	assert.Nil(t, err)
+	records, err := c.ReadCsv(tmpfile.Name(), profile.Comma())
+	assert.Nil(t, err)
+	t6records, err := c.Parser{Profile: profile}.Rw1minToStruct(records)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(t6records[2014]))
+
+	// EST is five hours behind UTC, also in July
+	parsedTime, _ := time.Parse("200601021504", "201407012201")
+	assert.Equal(t, c.ConvertToOle(parsedTime), t6records[2014][1].Date)
+	assert.Equal(t, float32(1.3689), t6records[2014][1].Open)
+	assert.Equal(t, float32(1.3691), t6records[2014][1].High)
+	assert.Equal(t, float32(1.3688), t6records[2014][1].Low)
+	assert.Equal(t, float32(1.369), t6records[2014][1].Close)
+	assert.Equal(t, int32(0), t6records[2014][1].Vol)
+
+	// an explicit input zone overrides the profile's
+	t6records, err = c.Parser{Profile: profile, Zones: c.Zones{In: time.UTC}}.Rw1minToStruct(records)
+	assert.Nil(t, err)
+	parsedTime, _ = time.Parse("200601021504", "201407011701")
+	assert.Equal(t, c.ConvertToOle(parsedTime), t6records[2014][1].Date)
+
+	invalid := profile
+	invalid.Zone = "Nowhere/Special"
+	assert.NotNil(t, invalid.Validate())
+
+	// bars stamped at their open need a period to be moved to their close
+	invalid = profile
+	invalid.Period = ""
+	assert.NotNil(t, invalid.Validate())
+	invalid.Stamp = "middle"
+	assert.NotNil(t, invalid.Validate())
+
+	// files stamped at the close are read as they are
+	closing := profile
+	closing.Stamp = ""
+	t6records, err = c.Parser{Profile: closing}.Rw1minToStruct(records)
+	assert.Nil(t, err)
+	parsedTime, _ = time.Parse("200601021504", "201407012200")
+	assert.Equal(t, c.ConvertToOle(parsedTime), t6records[2014][1].Date)
+}
+
+func TestBidAskSpread(t *testing.T) {
+	tmpfile := writeTempFile([]byte(dataBidAsk))
+	defer os.Remove(tmpfile.Name()) // clean up
+
+	profile, err := c.Pitrading1min.WithColumns("volume=0,bidopen=7,bidhigh=8,bidlow=9,bidclose=10")
+	assert.Nil(t, err)
+	t6records, err := c.Parser{Profile: profile}.FileToStruct(tmpfile.Name(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(t6records[2014]))
+	assert.Equal(t, float32(1.3761), t6records[2014][0].Open)
+	assert.InDelta(t, 0.0001, t6records[2014][0].Val, 1e-6)
+	assert.InDelta(t, 0.000175, t6records[2014][1].Val, 1e-6)
+
+	bars := c.Resample(t6records[2014], 5*time.Minute)
+	assert.Equal(t, 1, len(bars))
+	assert.InDelta(t, 0.0001375, bars[0].Val, 1e-6)
+
+	// daily bars without a spread column leave Val empty
+	dailyfile := writeTempFile([]byte(dailyStockData))
+	defer os.Remove(dailyfile.Name()) // clean up
+	records, _ := c.FileToCsv(dailyfile.Name())
+	daily, err := c.RwDailyToStruct(records)
+	assert.Nil(t, err)
+	for _, bar := range daily[0] {
+		assert.Equal(t, float32(0), bar.Val)
+	}
+}
+
+func TestAdjust(t *testing.T) {
+	tmpfile := writeTempFile([]byte(dailyStockData))
+	defer os.Remove(tmpfile.Name()) // clean up
+	records, _ := c.FileToCsv(tmpfile.Name())
+	t6records, _ := c.RwDailyToStruct(records)
+	t6records[0][0].Vol = 1000
+
+	actionfile := writeTempFile([]byte(dataActions))
+	defer os.Remove(actionfile.Name()) // clean up
+	actions, err := c.ReadCorporateActions(actionfile.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(actions["SPX"]))
+	assert.Equal(t, 1, len(actions["AAPL"]))
+
+	c.Adjust(t6records, actions["SPX"])
+
+	// before both the split and the dividend
+	dividendFactor := 1 - 4.2/426.35
+	assert.InDelta(t, 420.81/2*dividendFactor, t6records[0][0].Open, 0.001)
+	assert.InDelta(t, 419.64/2*dividendFactor, t6records[0][0].Close, 0.001)
+	assert.Equal(t, int32(2000), t6records[0][0].Vol)
+	// between the split and the dividend
+	assert.InDelta(t, 422.91*dividendFactor, t6records[0][16].Open, 0.001)
+	// on the ex-date
+	assert.Equal(t, float32(426.37), t6records[0][17].Open)
+}
+
+func TestMergeT6File(t *testing.T) {
+	tmpfile := writeTempFile([]byte(data1min))
+	defer os.Remove(tmpfile.Name()) // clean up
+	records, _ := c.FileToCsv(tmpfile.Name())
+	t6records, _ := c.Rw1minToStruct(records)
+
+	// bars 5 to 17 overlap the first run at 5 to 9
+	first := append([]model.ZorroT6(nil), t6records[2014][:10]...)
+	update := append([]model.ZorroT6(nil), t6records[2014][5:]...)
+	update[0].Close = 99
+
+	c.StructToT6File(map[int][]model.ZorroT6{2014: first}, "test/", "merge", false)
+	defer os.Remove("test/merge_2014.t6")
+
+	err := c.MergeStructToT6File(map[int][]model.ZorroT6{2014: update}, "test/", "merge", false, c.KeepOld)
+	assert.Nil(t, err)
+	merged := readt6("test/merge_2014.t6")
+	assert.Equal(t, 18, len(merged))
+	assert.Equal(t, float32(38.83), merged[12].Close)
+	for i := 1; i < len(merged); i++ {
+		assert.True(t, merged[i-1].Date > merged[i].Date)
+	}
+
+	err = c.MergeStructToT6File(map[int][]model.ZorroT6{2014: update}, "test/", "merge", false, c.KeepNew)
+	assert.Nil(t, err)
+	merged = readt6("test/merge_2014.t6")
+	assert.Equal(t, 18, len(merged))
+	assert.Equal(t, float32(99), merged[12].Close)
+
+	_, err = c.ParseMergePolicy("keep-both")
+	assert.NotNil(t, err)
+}
+
+func TestT6Writer(t *testing.T) {
+	tmpfile := writeTempFile([]byte(data1min))
+	defer os.Remove(tmpfile.Name()) // clean up
+	records, _ := c.FileToCsv(tmpfile.Name())
+	t6records, _ := c.Rw1minToStruct(records)
+	t6records[2014][0].Val = 0.5
+
+	var expected bytes.Buffer
+	binary.Write(&expected, binary.LittleEndian, t6records[2014])
+
+	var buf bytes.Buffer
+	w := c.NewT6Writer(&buf)
+	assert.Nil(t, w.Write(t6records[2014]))
+	assert.Nil(t, w.Flush())
+	assert.Equal(t, expected.Bytes(), buf.Bytes())
+
+	w = c.NewT6Writer(failingWriter{})
+	err := w.Write(t6records[2014])
+	if err == nil {
+		err = w.Flush()
+	}
+	assert.NotNil(t, err)
+}
+
+func TestAtomicT6File(t *testing.T) {
+	tmpfile := writeTempFile([]byte(data1min))
+	defer os.Remove(tmpfile.Name()) // clean up
+	records, _ := c.FileToCsv(tmpfile.Name())
+	t6records, _ := c.Rw1minToStruct(records)
+
+	err := c.StructToT6File(t6records, "test/", "atomic", false)
+	assert.Nil(t, err)
+	defer os.Remove("test/atomic_2014.t6")
+	defer os.Remove("test/atomic_2015.t6")
+
+	info, err := os.Stat("test/atomic_2014.t6")
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	temps, _ := filepath.Glob("test/.atomic*")
+	assert.Empty(t, temps)
+
+	// missing directories are created, but not over a file
+	err = c.StructToT6File(t6records, "test/missing/", "atomic", false)
+	assert.Nil(t, err)
+	os.RemoveAll("test/missing")
+	ioutil.WriteFile("test/blocked", nil, 0644)
+	defer os.Remove("test/blocked")
+	err = c.StructToT6File(t6records, "test/blocked/", "atomic", false)
+	assert.NotNil(t, err)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrShortWrite
+}
+
+func TestInspect(t *testing.T) {
+	tmpfile := writeTempFile([]byte(data1min))
+	defer os.Remove(tmpfile.Name()) // clean up
+	records, _ := c.FileToCsv(tmpfile.Name())
+	t6records, _ := c.Rw1minToStruct(records)
+
+	c.StructToT6File(t6records, "test/", "inspect", false)
+	defer os.Remove("test/inspect_2014.t6")
+	defer os.Remove("test/inspect_2015.t6")
+
+	stats := c.Inspect(readt6("test/inspect_2014.t6"))
+	assert.Equal(t, 18, stats.Records)
+	first, _ := time.Parse("200601021504", "201401020931")
+	last, _ := time.Parse("200601021504", "201401020948")
+	assert.Equal(t, first, stats.First)
+	assert.Equal(t, last, stats.Last)
+	assert.True(t, stats.Descending)
+	assert.Equal(t, float32(38.51), stats.MinPrice)
+	assert.Equal(t, float32(38.88), stats.MaxPrice)
+	assert.Equal(t, 0.0, stats.ZeroVolume)
+	assert.Equal(t, time.Minute, stats.Period)
+
+	// csv order is oldest first
+	ascending, _ := c.Rw1minToStruct(records)
+	stats = c.Inspect(ascending[2015])
+	assert.False(t, stats.Descending)
+	assert.Equal(t, 1, stats.Misordered)
+
+	var buf bytes.Buffer
+	err := t6converter.Inspect(context.Background(), "test/inspect_2014.t6", &buf)
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), "last bar:    2014-01-02 09:48:00")
+	assert.Contains(t, buf.String(), "bar period:  1m0s")
+}
+
+func TestVerify(t *testing.T) {
+	tmpfile := writeTempFile([]byte(data1min))
+	defer os.Remove(tmpfile.Name()) // clean up
+	records, _ := c.FileToCsv(tmpfile.Name())
+	t6records, _ := c.Rw1minToStruct(records)
+
+	c.StructToT6File(t6records, "test/", "verify", false)
+	defer os.Remove("test/verify_2014.t6")
+	defer os.Remove("test/verify_2015.t6")
+
+	problems, err := c.VerifyT6File("test/verify_2014.t6")
+	assert.Nil(t, err)
+	assert.Empty(t, problems)
+
+	var buf bytes.Buffer
+	passed, err := t6converter.Verify(context.Background(), "test/verify_2014.t6", &buf)
+	assert.Nil(t, err)
+	assert.True(t, passed)
+
+	// wrong year, ascending, high below close and a negative price
+	broken := readt6("test/verify_2015.t6")
+	broken[0], broken[1] = broken[1], broken[0]
+	broken[0].High = broken[0].Close - 1
+	broken[1].Low = -1
+	problems = c.VerifyT6(broken, 2014)
+	assert.Equal(t, 5, len(problems))
+
+	os.Rename("test/verify_2015.t6", "test/verify_2014.t6")
+	problems, _ = c.VerifyT6File("test/verify_2014.t6")
+	assert.Equal(t, 2, len(problems))
+
+	// the year suffix is matched ignoring case
+	os.Rename("test/verify_2014.t6", "test/VERIFY_2014.T6")
+	problems, _ = c.VerifyT6File("test/VERIFY_2014.T6")
+	assert.Equal(t, 2, len(problems))
+	os.Rename("test/VERIFY_2014.T6", "test/verify_2014.t6")
+
+	ioutil.WriteFile("test/verify_2014.t6", make([]byte, 33), 0644)
+	buf.Reset()
+	passed, err = t6converter.Verify(context.Background(), "test/verify_2014.t6", &buf)
+	assert.Nil(t, err)
+	assert.False(t, passed)
+	assert.Contains(t, buf.String(), "size 33 is not a multiple of 32 bytes")
+}
+
 func readt6(path string) []model.ZorroT6 {
 
 	file, err := os.Open(path)
@@ -349,4 +1564,49 @@ const dailyFxData string = `Date, Open, High, Low, Close
 20040513, 2.93540000, 2.94500000, 2.90420000, 2.91700000
 20040514, 2.91840000, 2.93730000, 2.90880000, 2.93190000
 
-`
\ No newline at end of file
+`
+const dataTicks string = `20140102,09:30:00,38.88,A
+20140102,09:30:00.250,38.87,B
+20140102,09:30:01.250,38.88,A
+20140102,09:30:01.500,38.87,B
+20150102,09:30:00,38.61,A
+`
+
+const dataOptions string = `date,underlying,expiry,type,strike,bid,ask,volume,openinterest
+20140102,184.1,20140117,C,185,1.05,1.1,1200,23410
+20140102,184.1,20140117,CE,180,4.6,4.7,310,8500
+20140103,183.5,20140117,P,180,1.15,1.2,530,12011
+20150102,205.4,20150116,C,205,2.3,2.35,4110,51002
+`
+
+const dataGaps string = `20140102,09:30,38.88,38.88,38.82,38.85,67004
+20140102,09:31,38.88,38.88,38.82,38.82,2805
+20140102,09:34,38.78,38.81,38.78,38.81,3380
+20140102,09:34,38.81,38.84,38.78,38.83,12083
+20140102,09:33,38.82,38.82,38.81,38.81,1320
+20140102,09:35,38.8,38.83,38.8,38.83,3067
+20140103,09:30,38.8,38.82,38.75,38.75,2791
+`
+
+const dataHistData string = `20140102 170000;1.376100;1.376200;1.375900;1.376000;0
+20140701 170000;1.368900;1.369100;1.368800;1.369000;0
+`
+
+const dataMT5 string = "<DATE>\t<TIME>\t<OPEN>\t<HIGH>\t<LOW>\t<CLOSE>\t<TICKVOL>\t<VOL>\t<SPREAD>\n" +
+	"2014.01.02\t13:00:00\t1.37610\t1.37620\t1.37590\t1.37600\t118\t0\t12\n" +
+	"2014.01.02\t14:00:00\t1.37600\t1.37650\t1.37580\t1.37640\t97\t0\t12\n"
+
+const dataBidAsk string = `20140102,09:30,1.3761,1.3762,1.3759,1.3760,1.3760,1.3761,1.3758,1.3759
+20140102,09:31,1.3760,1.3765,1.3758,1.3764,1.3758,1.3763,1.3756,1.3763
+`
+
+const dataSemicolon string = `symbol;timestamp;open;high;low;close;spread
+EURUSD;02.01.2014 09:30:00;1.3670;1.3672;1.3669;1.3671;0.0001
+EURUSD;02.01.2014 09:31:00;1.3671;1.3675;1.3668;1.3670;0.0002
+`
+
+const dataActions string = `symbol,date,split,dividend
+SPX,20010605,2:1,
+SPX,20010606,,4.2
+AAPL,20050228,2,0
+`
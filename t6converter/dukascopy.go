@@ -0,0 +1,134 @@
+package t6converter
+
+import (
+	"context"
+	c "github.com/dan-lind/t6converter/converters"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// processBi5Files converts the Dukascopy bi5 files below opts.InputDir. Each of
+// them holds an hour of ticks, so they are converted all years of a symbol at
+// a time and reported by the directory of the symbol.
+func processBi5Files(ctx context.Context, opts Options) *Report {
+
+	paths, errc := walkFiles(ctx, opts.InputDir, ".bi5")
+
+	var symbols []string
+	files := make(map[string][]string)
+	for path := range paths {
+		// SYMBOL/YYYY/MM/DD/HHh_ticks.bi5, walked in lexical and so in time order
+		symbol := filepath.Dir(filepath.Dir(filepath.Dir(filepath.Dir(path))))
+		if _, ok := files[symbol]; !ok {
+			symbols = append(symbols, symbol)
+		}
+		files[symbol] = append(files[symbol], path)
+	}
+
+	rep := &Report{}
+	names := newOutputNames()
+	slots := make(chan struct{}, opts.Writers)
+	if err := <-errc; err != nil {
+		rep.walkDone(ctx, err)
+		return rep
+	}
+
+	dirs := make(chan string)
+	var wg sync.WaitGroup
+	wg.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		go func() {
+			for dir := range dirs {
+				rep.add(ctx, dir, convertBi5Symbol(ctx, files[dir], opts, names, slots))
+			}
+			wg.Done()
+		}()
+	}
+
+	for _, dir := range symbols {
+		if err := ctx.Err(); err != nil {
+			rep.add(ctx, dir, err)
+			continue
+		}
+		dirs <- dir
+	}
+	close(dirs)
+	wg.Wait()
+	rep.walkDone(ctx, ctx.Err())
+
+	return rep
+}
+
+// convertBi5Symbol converts the bi5 files of a symbol, in time order, to t1
+// ticks or to t6 bars of opts.Resample, which defaults to a minute. The ticks
+// of each hour are converted as soon as they are read, and each year is
+// written once no later tick can change it. Only a year of bars is kept, and a
+// day of ticks, see TickSpool. A bar spanning new year is built from the ticks
+// of both years.
+func convertBi5Symbol(ctx context.Context, paths []string, opts Options, names *outputNames, slots chan struct{}) error {
+	period := opts.Resample
+	if period == 0 {
+		period = time.Minute
+	}
+	bars := c.NewTickBars(period)
+
+	// {dir} is the directory holding the symbol directory
+	symbolDir := filepath.Dir(filepath.Dir(filepath.Dir(filepath.Dir(paths[0]))))
+	symbol := c.Bi5Symbol(paths[0])
+	w := newInputWriter(ctx, symbolDir, symbol, opts.outputName(symbolDir, symbol), opts, names, slots)
+
+	// ticks are spooled a day at a time until their year is written
+	var spool *c.TickSpool
+	var spoolYear int
+	defer func() {
+		if spool != nil {
+			spool.Close()
+		}
+	}()
+	writeTicks := func() error {
+		if spool == nil {
+			return nil
+		}
+		err := w.tickSpool(spoolYear, spool)
+		spool.Close()
+		spool = nil
+		return err
+	}
+
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ticks, err := c.Bi5FileToTicks(path)
+		if err != nil {
+			return err
+		}
+		if opts.Mode == Bi5ToT1 {
+			// the ticks of an hour are of a single year
+			for year, records := range c.TicksToT1(ticks, opts.Bids) {
+				if spool == nil || year != spoolYear {
+					if err := writeTicks(); err != nil {
+						return err
+					}
+					spool, spoolYear = c.NewTickSpool(), year
+				}
+				if err := spool.Add(records...); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		for _, tick := range ticks {
+			bars.Add(tick)
+		}
+		if err := w.bars(bars.Finished()); err != nil {
+			return err
+		}
+	}
+
+	if opts.Mode == Bi5ToT1 {
+		return writeTicks()
+	}
+	return w.bars(bars.Bars())
+}
@@ -0,0 +1,38 @@
+package t6converter
+
+import (
+	"context"
+	"fmt"
+	c "github.com/dan-lind/t6converter/converters"
+	"io"
+)
+
+// Inspect writes the statistics of the t6 file at path, or of every t6 file
+// below path if it is a directory, to w.
+func Inspect(ctx context.Context, path string, w io.Writer) error {
+	paths, errc := walkFiles(ctx, path, "t6")
+
+	var firstErr error
+	for p := range paths {
+		if firstErr != nil {
+			continue
+		}
+
+		records, err := c.T6FileToStruct(p)
+		if err == nil {
+			_, err = fmt.Fprintf(w, "%v\n", p)
+		}
+		if err == nil {
+			err = c.Inspect(records).WriteText(w)
+		}
+		if err == nil {
+			_, err = fmt.Fprintln(w)
+		}
+		firstErr = err
+	}
+
+	if err := <-errc; err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
@@ -0,0 +1,256 @@
+package t6converter
+
+import (
+	"context"
+	"fmt"
+	c "github.com/dan-lind/t6converter/converters"
+	"github.com/dan-lind/t6converter/model"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// outputNames detects inputs that would be written to the same output file,
+// which would otherwise silently overwrite each other. Inputs merging into the
+// same t6 file may share it, their writes are serialized.
+type outputNames struct {
+	mu     sync.Mutex
+	owners map[string]*outputOwner // by output name
+}
+
+type outputOwner struct {
+	input  string // the input claiming the name, see claim
+	symbol string
+	shared bool
+	spill  bool       // the input only spills into the name
+	writer string     // the input that wrote the file last
+	mu     sync.Mutex // held while the file is written
+}
+
+func newOutputNames() *outputNames {
+	return &outputNames{owners: make(map[string]*outputOwner)}
+}
+
+// claim reserves name for input of symbol and returns its owner, whose lock
+// is held while writing the file. It fails if another input claimed the name,
+// unless both claims are shared, or one of them is a spill and both inputs are
+// of the same symbol. An input spills into a year when its only bars there
+// close the period that started in the year before, such as the last bar of
+// SPY_2014.csv closing on new year's midnight. A spill gives way to the input
+// claiming the name for bars of its own. Names are compared ignoring case,
+// like the file systems Zorro runs on do.
+func (n *outputNames) claim(input string, symbol string, name string, shared bool, spill bool) (*outputOwner, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	key := strings.ToLower(filepath.Clean(name))
+	owner, ok := n.owners[key]
+	if !ok {
+		owner = &outputOwner{input: input, symbol: symbol, shared: shared, spill: spill}
+		n.owners[key] = owner
+	}
+
+	switch {
+	case owner.input == input:
+		owner.spill = owner.spill && spill
+	case shared && owner.shared:
+	case (spill || owner.spill) && symbol == owner.symbol:
+		if owner.spill && !spill {
+			owner.input, owner.spill = input, false
+		}
+	default:
+		return nil, fmt.Errorf("output %v of %v collides with the output of %v", name, input, owner.input)
+	}
+	return owner, nil
+}
+
+// An input is an input file of the walk. Inputs with the same output names,
+// but for their years, are converted one after the other in walk order, so
+// that their claims are decided and their merges applied in that order.
+type input struct {
+	path  string
+	after <-chan struct{} // closed once the input before it is done, nil if none
+	done  chan struct{}
+}
+
+// orderInputs passes on paths as inputs, each after the last one before it in
+// walk order with the same output names.
+func (opts Options) orderInputs(paths <-chan string) <-chan input {
+	inputs := make(chan input)
+	go func() {
+		defer close(inputs)
+		last := make(map[string]chan struct{})
+		for path := range paths {
+			// any year will do, the year is expanded alike for every input
+			key := strings.ToLower(opts.outputName(path, opts.symbol(path))(1))
+			in := input{path: path, after: last[key], done: make(chan struct{})}
+			last[key] = in.done
+			inputs <- in
+		}
+	}()
+	return inputs
+}
+
+// An inputWriter writes the converted records of a single input a year at a
+// time, as soon as they are read. The writers of all inputs share slots, which
+// bounds the number of files written at once.
+type inputWriter struct {
+	ctx     context.Context
+	input   string
+	symbol  string
+	name    func(year int) string
+	period  time.Duration // of the written bars, 0 if not known
+	daily   bool
+	merge   c.MergePolicy
+	names   *outputNames
+	slots   chan struct{}
+	written map[string]bool // files written by this input, later years of a file are merged into them
+}
+
+func newInputWriter(ctx context.Context, input string, symbol string, name func(year int) string, opts Options, names *outputNames, slots chan struct{}) *inputWriter {
+	period := opts.Resample
+	if period == 0 {
+		period, _ = opts.Profile.BarPeriod()
+	}
+	return &inputWriter{
+		ctx:     ctx,
+		input:   input,
+		symbol:  symbol,
+		name:    name,
+		period:  period,
+		daily:   opts.Daily,
+		merge:   opts.Merge,
+		names:   names,
+		slots:   slots,
+		written: make(map[string]bool),
+	}
+}
+
+// write claims name, shared if other inputs may merge into it as well, and
+// calls fn to write it once a slot is free. Whether another input wrote the
+// file before is passed to fn.
+func (w *inputWriter) write(name string, shared bool, spill bool, fn func(others bool) error) error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	owner, err := w.names.claim(w.input, w.symbol, name, shared, spill)
+	if err != nil {
+		return err
+	}
+	owner.mu.Lock()
+	defer owner.mu.Unlock()
+
+	select {
+	case w.slots <- struct{}{}:
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	}
+	defer func() { <-w.slots }()
+
+	if err := fn(owner.writer != "" && owner.writer != w.input); err != nil {
+		return err
+	}
+	owner.writer = w.input
+	w.written[name] = true
+	return nil
+}
+
+// bars writes recordMap to the t6 file of each year.
+func (w *inputWriter) bars(recordMap map[int][]model.ZorroT6) error {
+	years := make([]int, 0, len(recordMap))
+	for year := range recordMap {
+		years = append(years, year)
+	}
+	sort.Ints(years)
+
+	for _, year := range years {
+		name := w.name(year)
+		bars := map[int][]model.ZorroT6{year: recordMap[year]}
+		err := w.write(name, w.merge != c.Overwrite, w.spills(year, recordMap[year]), func(others bool) error {
+			policy := w.merge
+			switch {
+			case policy != c.Overwrite:
+			case w.written[name]:
+				// bars of a year the input returned to, keep those written before
+				policy = c.KeepNew
+			case others:
+				// a spill of the year before and the bars of the year, the
+				// parts of a bar spanning new year are combined
+				policy = c.Combine
+			}
+			return c.WriteT6Files(bars, w.name, policy)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spills returns whether all bars of year close within the first period of
+// the year, which started in the year before, see outputNames.claim.
+func (w *inputWriter) spills(year int, bars []model.ZorroT6) bool {
+	if w.daily || len(bars) == 0 {
+		return false
+	}
+	limit := c.ConvertToOle(time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC).Add(w.period))
+	for _, bar := range bars {
+		if bar.Date > limit {
+			return false
+		}
+	}
+	return true
+}
+
+// ticks writes the ticks of year to its t1 file.
+func (w *inputWriter) ticks(year int, ticks []model.ZorroT1) error {
+	name := w.name(year)
+	return w.write(name, false, false, func(bool) error {
+		if w.written[name] {
+			written, err := c.T1FileToStruct(name)
+			if err != nil {
+				return err
+			}
+			ticks = append(written, ticks...)
+		}
+		return c.WriteT1Files(map[int][]model.ZorroT1{year: ticks}, w.name)
+	})
+}
+
+// tickSpool writes the ticks of year collected in spool to its t1 file.
+func (w *inputWriter) tickSpool(year int, spool *c.TickSpool) error {
+	name := w.name(year)
+	return w.write(name, false, false, func(bool) error { return spool.WriteT1File(name) })
+}
+
+// contracts writes the contracts of year to its t8 file.
+func (w *inputWriter) contracts(year int, contracts []model.ZorroT8) error {
+	name := w.name(year)
+	return w.write(name, false, false, func(bool) error {
+		if w.written[name] {
+			written, err := c.T8FileToStruct(name)
+			if err != nil {
+				return err
+			}
+			contracts = append(written, contracts...)
+		}
+		return c.WriteT8Files(map[int][]model.ZorroT8{year: contracts}, w.name)
+	})
+}
+
+// gaps writes report next to the output files of the input, if it has issues.
+// The report is named after the input, several inputs such as SPY_2014.csv and
+// SPY_2015.csv may write the output files of a symbol.
+func (w *inputWriter) gaps(report c.GapReport) error {
+	if !report.HasIssues() {
+		return nil
+	}
+	base := filepath.Base(w.input)
+	for _, suffix := range []string{".gz", ".bz2"} {
+		base = strings.TrimSuffix(base, suffix)
+	}
+	name := filepath.Join(filepath.Dir(w.name(0)), strings.TrimSuffix(base, filepath.Ext(base))+"_gaps")
+	return w.write(name+".json", false, false, func(bool) error { return c.WriteGapReportFile(report, name) })
+}
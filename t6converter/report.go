@@ -0,0 +1,118 @@
+package t6converter
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// A FileResult is the outcome of converting a single input file.
+type FileResult struct {
+	Path    string `json:"path"`
+	Error   string `json:"error,omitempty"`
+	Skipped bool   `json:"skipped,omitempty"` // the run was canceled before the file was completely converted
+}
+
+// A Report collects the outcome of every file of a run, so that a failing file
+// does not stop the others from being converted.
+type Report struct {
+	mu        sync.Mutex
+	Converted int          `json:"converted"`
+	Failed    int          `json:"failed"`
+	Skipped   int          `json:"skipped,omitempty"`
+	Files     []FileResult `json:"files"`
+	WalkError string       `json:"walkError,omitempty"`
+	Canceled  bool         `json:"canceled,omitempty"` // the run was stopped before all files were converted
+}
+
+// add records the outcome of path, err is nil if it was converted. A file
+// that stopped because ctx was canceled is recorded as skipped rather than
+// failed. It is safe to call from several goroutines.
+func (r *Report) add(ctx context.Context, path string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := FileResult{Path: path}
+	if err != nil && ctx.Err() != nil && errors.Cause(err) == ctx.Err() {
+		result.Skipped = true
+		r.Skipped++
+	} else if err != nil {
+		result.Error = err.Error()
+		r.Failed++
+	} else {
+		r.Converted++
+	}
+	r.Files = append(r.Files, result)
+}
+
+// walkDone records the outcome of the directory walk, which ends with the
+// context's error when the run was canceled.
+func (r *Report) walkDone(ctx context.Context, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ctx.Err() != nil {
+		r.Canceled = true
+		if err == ctx.Err() {
+			return
+		}
+	}
+	if err != nil {
+		r.WalkError = err.Error()
+	}
+}
+
+// HasFailures reports whether any file or the directory walk failed.
+func (r *Report) HasFailures() bool {
+	return r.Failed > 0 || r.WalkError != ""
+}
+
+// Failures returns a copy of the report holding only the failed files.
+func (r *Report) Failures() *Report {
+	failures := &Report{Converted: r.Converted, Failed: r.Failed, Skipped: r.Skipped, WalkError: r.WalkError, Canceled: r.Canceled}
+	for _, file := range r.Files {
+		if file.Error != "" {
+			failures.Files = append(failures.Files, file)
+		}
+	}
+	return failures
+}
+
+// WriteJSON writes the report as indented json, files sorted by path. It is
+// safe to call while files are still being added.
+func (r *Report) WriteJSON(w io.Writer) error {
+	r.mu.Lock()
+	snapshot := Report{
+		Converted: r.Converted,
+		Failed:    r.Failed,
+		Skipped:   r.Skipped,
+		Files:     append([]FileResult(nil), r.Files...),
+		WalkError: r.WalkError,
+		Canceled:  r.Canceled,
+	}
+	r.mu.Unlock()
+
+	sort.Slice(snapshot.Files, func(i, j int) bool {
+		return snapshot.Files[i].Path < snapshot.Files[j].Path
+	})
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&snapshot)
+}
+
+// WriteJSONFile writes the report to the file at path.
+func (r *Report) WriteJSONFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := r.WriteJSON(file); err != nil {
+		return err
+	}
+	return file.Close()
+}
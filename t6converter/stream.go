@@ -0,0 +1,47 @@
+package t6converter
+
+import (
+	c "github.com/dan-lind/t6converter/converters"
+	"github.com/dan-lind/t6converter/model"
+	"io"
+	"sort"
+)
+
+// A Reader decodes the bars of an input stream, grouped by year or under key 0
+// for daily bars.
+type Reader interface {
+	ReadBars(r io.Reader) (map[int][]model.ZorroT6, error)
+}
+
+// CSVReader reads csv bars with the layout and zones of Parser.
+type CSVReader struct {
+	Parser c.Parser
+	Daily  bool
+}
+
+func (r CSVReader) ReadBars(in io.Reader) (map[int][]model.ZorroT6, error) {
+	return r.Parser.NewBarReader(in, r.Daily).ReadAll()
+}
+
+// ConvertStream reads all bars from r and writes them to w as a single t6
+// file, newest first regardless of their year.
+func ConvertStream(r io.Reader, w io.Writer, reader Reader) error {
+	recordMap, err := reader.ReadBars(r)
+	if err != nil {
+		return err
+	}
+
+	var records []model.ZorroT6
+	for _, bars := range recordMap {
+		records = append(records, bars...)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Date > records[j].Date
+	})
+
+	t6 := c.NewT6Writer(w)
+	if err := t6.Write(records); err != nil {
+		return err
+	}
+	return t6.Flush()
+}
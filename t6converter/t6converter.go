@@ -0,0 +1,408 @@
+// Package t6converter converts directories of csv price history and Dukascopy
+// tick files to Zorro's t6, t1 and t8 files, and t6 files back to csv. It is the pipeline behind the
+// t6converter command, for programs that want to convert in process.
+package t6converter
+
+import (
+	"context"
+	"errors"
+	c "github.com/dan-lind/t6converter/converters"
+	"github.com/dan-lind/t6converter/model"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Mode selects what Convert converts.
+type Mode string
+
+const (
+	CsvToT6 Mode = "csvtot6" // bars, including MetaTrader 4 hst files, to t6 files
+	CsvToT1 Mode = "csvtot1" // ticks to t1 files
+	CsvToT8 Mode = "csvtot8" // options chains to t8 files
+	T6ToCsv Mode = "t6tocsv" // t6 files back to csv
+	Bi5ToT1 Mode = "bi5tot1" // Dukascopy bi5 ticks to t1 files
+	Bi5ToT6 Mode = "bi5tot6" // Dukascopy bi5 ticks to t6 bars
+)
+
+// Options holds the settings of a conversion run. The zero value of every
+// field but InputDir and OutputDir is a valid default.
+type Options struct {
+	InputDir  string
+	OutputDir string
+	Mode      Mode // defaults to CsvToT6
+	Daily     bool // daily bars, written to a single file instead of one per year
+
+	Reader   Reader                         // decodes CsvToT6 input, defaults to a CSVReader for Profile and Zones
+	Profile  c.Profile                      // input layout, defaults to the Pitrading layout for Daily
+	Zones    c.Zones                        // defaults to UTC in and out
+	Resample time.Duration                  // zero keeps the source period
+	Gaps     bool                           // write a gap report for files with missing, duplicate or out of order bars
+	Actions  map[string][]c.CorporateAction // by symbol, nil unless bars are adjusted
+	Merge    c.MergePolicy
+	Bids     bool // Bi5ToT1 also writes bid quotes, as negative prices
+
+	NameTemplate  c.NameTemplate // output file names, defaults to Zorro's for the mode and Daily in the input's directory
+	Flat          bool           // write default names directly into OutputDir instead of mirroring the input tree
+	SymbolPattern *regexp.Regexp // picks the symbol from input file names, see c.SymbolMatching
+
+	Workers int // files read and converted concurrently, defaults to GOMAXPROCS
+	Writers int // files written concurrently, defaults to Workers
+}
+
+// A result is the outcome of converting a single input file.
+type result struct {
+	path string
+	err  error
+}
+
+// Convert converts every input file below opts.InputDir to opts.OutputDir. A
+// file that fails is recorded in the returned report and does not stop the
+// others, the error is only set if the run could not start. Output files are
+// written a year at a time while the input is read. Cancelling ctx stops the
+// conversion before the next year is written, records the files it stopped as
+// skipped and marks the report Canceled.
+func Convert(ctx context.Context, opts Options) (*Report, error) {
+	if opts.Mode == "" {
+		opts.Mode = CsvToT6
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
+	if opts.Writers <= 0 {
+		opts.Writers = opts.Workers
+	}
+	if opts.InputDir == "" || opts.OutputDir == "" {
+		return nil, errors.New("input and output directory are required")
+	}
+	if opts.NameTemplate != "" {
+		if _, err := c.ParseNameTemplate(string(opts.NameTemplate)); err != nil {
+			return nil, err
+		}
+	}
+	if !strings.HasSuffix(opts.OutputDir, string(os.PathSeparator)) && !strings.HasSuffix(opts.OutputDir, "/") {
+		opts.OutputDir += string(os.PathSeparator)
+	}
+
+	switch opts.Mode {
+	case CsvToT6, CsvToT1, CsvToT8:
+		return processFiles(ctx, opts), nil
+	case T6ToCsv:
+		return processT6Files(ctx, opts), nil
+	case Bi5ToT1, Bi5ToT6:
+		if opts.Daily {
+			return nil, errors.New("bi5 files are converted to yearly files, daily is not supported")
+		}
+		return processBi5Files(ctx, opts), nil
+	}
+	return nil, errors.New("unknown mode " + string(opts.Mode))
+}
+
+// digester reads inputs, converts the corresponding files and sends the
+// outcome on res until inputs is closed. Once ctx is done, the files left are
+// not converted but still reported.
+func digester(ctx context.Context, inputs <-chan input, res chan<- result, opts Options, names *outputNames, slots chan struct{}) {
+	for in := range inputs { // HLpaths
+		if in.after != nil {
+			<-in.after
+		}
+		err := ctx.Err()
+		if err == nil {
+			err = convertFile(ctx, in.path, opts, names, slots)
+		}
+		close(in.done)
+		// No select needed, processFiles receives until res is closed.
+		res <- result{path: in.path, err: err}
+	}
+}
+
+// convertFile converts the input file at path to opts.OutputDir. Csv input is
+// written a year at a time as soon as the file moves on to the next year,
+// unless a dividend adjustment needs the bars of all years.
+func convertFile(ctx context.Context, path string, opts Options, names *outputNames, slots chan struct{}) error {
+	symbol := opts.symbol(path)
+	w := newInputWriter(ctx, path, symbol, opts.outputName(path, symbol), opts, names, slots)
+
+	switch opts.Mode {
+	case CsvToT1:
+		return c.Parser{Profile: opts.Profile, Zones: opts.Zones}.StreamTicks(path, w.ticks)
+	case CsvToT8:
+		return c.Parser{Profile: opts.Profile, Zones: opts.Zones}.StreamOptions(path, w.contracts)
+	}
+
+	actions := opts.Actions[strings.ToUpper(symbol)]
+	var resampler *c.Resampler
+	if opts.Resample > 0 {
+		resampler = c.NewResampler(opts.Resample)
+	}
+	// resampled bars wait until no later bar can close in their year, the bar
+	// being built is carried into the next year of the file
+	resampled := make(map[int][]model.ZorroT6)
+	convert := func(records map[int][]model.ZorroT6) error {
+		if len(actions) > 0 {
+			c.Adjust(records, actions)
+		}
+		if resampler == nil {
+			return w.bars(records)
+		}
+
+		for year, bars := range resampler.AddYears(records, opts.Daily) {
+			resampled[year] = append(resampled[year], bars...)
+		}
+		done := make(map[int][]model.ZorroT6)
+		for year, bars := range resampled {
+			if open, ok := resampler.OpenYear(); !ok || (year < open && !opts.Daily) {
+				done[year] = bars
+				delete(resampled, year)
+			}
+		}
+		return w.bars(done)
+	}
+
+	period, err := opts.Profile.BarPeriod()
+	if err != nil {
+		return err
+	}
+	if opts.Daily {
+		period = 24 * time.Hour
+	}
+	gaps := c.NewGapChecker(path, period)
+	var records map[int][]model.ZorroT6
+
+	switch {
+	case hasSuffix(path, []string{".hst"}):
+		// bar times are the server time of the broker, -tz-in, profiles describe csv files
+		records, err = c.Parser{Zones: opts.Zones, Gaps: gaps}.HstFileToStruct(path, opts.Daily)
+	case opts.Reader != nil:
+		gaps = nil
+		records, err = readFile(path, opts.Reader)
+	default:
+		p := c.Parser{Profile: opts.Profile, Zones: opts.Zones, Gaps: gaps}
+		if hasDividend(actions) {
+			// a dividend is scaled by the last close before its ex-date,
+			// which may be in any year of the file
+			records, err = p.FileToStruct(path, opts.Daily)
+		} else {
+			err = p.StreamBars(path, opts.Daily, func(year int, bars []model.ZorroT6) error {
+				return convert(map[int][]model.ZorroT6{year: bars})
+			})
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if records != nil {
+		if err := convert(records); err != nil {
+			return err
+		}
+	}
+	if resampler != nil {
+		for year, bars := range resampler.FlushYears(opts.Daily) {
+			resampled[year] = append(resampled[year], bars...)
+		}
+		if err := w.bars(resampled); err != nil {
+			return err
+		}
+	}
+
+	if opts.Gaps && gaps != nil {
+		return w.gaps(gaps.Report())
+	}
+	return nil
+}
+
+func hasDividend(actions []c.CorporateAction) bool {
+	for _, action := range actions {
+		if action.Dividend > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// readFile decodes the file at path with reader, which is handed the
+// decompressed contents of compressed files and each file of a zip archive in
+// turn.
+func readFile(path string, reader Reader) (map[int][]model.ZorroT6, error) {
+	var t6records = make(map[int][]model.ZorroT6)
+	err := c.ReadInputs(path, func(r io.Reader) error {
+		recordMap, err := reader.ReadBars(r)
+		for year, bars := range recordMap {
+			t6records[year] = append(t6records[year], bars...)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t6records, nil
+}
+
+func processFiles(ctx context.Context, opts Options) *Report {
+
+	suffixes := c.InputSuffixes
+	if opts.Mode == CsvToT6 {
+		// MetaTrader 4 history files hold bars as well
+		suffixes = append([]string{".hst"}, suffixes...)
+	}
+	paths, errc := walkFiles(ctx, opts.InputDir, suffixes...)
+
+	// The digesters write a year at a time, sharing a fixed number of slots
+	// so that no more than opts.Writers files are written at once.
+	rep := &Report{}
+	names := newOutputNames()
+	slots := make(chan struct{}, opts.Writers)
+
+	// Start a fixed number of goroutines to read and digest files.
+	res := make(chan result) // HLc
+	inputs := opts.orderInputs(paths)
+	var wg sync.WaitGroup
+	wg.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		go func() {
+			digester(ctx, inputs, res, opts, names, slots) // HLc
+			wg.Done()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(res) // HLc
+	}()
+	// End of pipeline. OMIT
+
+	for r := range res {
+		rep.add(ctx, r.path, r.err)
+	}
+
+	// Check whether the Walk failed.
+	rep.walkDone(ctx, <-errc) // HLerrc
+
+	return rep
+}
+
+// symbol returns the symbol of the input file at path. MetaTrader 4 history
+// files are named after symbol and period, their symbol is read from the
+// header unless opts.SymbolPattern picks it.
+func (opts Options) symbol(path string) string {
+	if opts.SymbolPattern == nil && hasSuffix(path, []string{".hst"}) {
+		if symbol, err := c.HstFileSymbol(path); err == nil && symbol != "" {
+			return symbol
+		}
+	}
+	return c.SymbolMatching(opts.SymbolPattern, path)
+}
+
+// outputName returns the name of the output file for each year of symbol,
+// read from the input at path, expanding opts.NameTemplate.
+func (opts Options) outputName(path string, symbol string) func(year int) string {
+	template := opts.NameTemplate
+	if template == "" {
+		switch {
+		case opts.Mode == CsvToT1 || opts.Mode == Bi5ToT1:
+			template = c.YearlyT1
+		case opts.Mode == CsvToT8:
+			template = c.YearlyT8
+		case opts.Daily:
+			template = c.DailyT6
+		default:
+			template = c.YearlyT6
+		}
+		if !opts.Flat {
+			template = "{dir}/" + template
+		}
+	}
+
+	dir := opts.inputDir(path)
+	return func(year int) string {
+		return filepath.Join(opts.OutputDir, template.Name(dir, symbol, year))
+	}
+}
+
+// inputDir returns the directory of the input at path relative to
+// opts.InputDir, or an empty string for the input directory itself.
+func (opts Options) inputDir(path string) string {
+	dir, err := filepath.Rel(opts.InputDir, filepath.Dir(path))
+	if err != nil || dir == "." || strings.HasPrefix(dir, "..") {
+		return ""
+	}
+	return dir
+}
+
+// processT6Files converts every t6 file below opts.InputDir back to a
+// date,time,OHLCV csv file in the corresponding directory of opts.OutputDir.
+func processT6Files(ctx context.Context, opts Options) *Report {
+
+	paths, errc := walkFiles(ctx, opts.InputDir, "t6")
+	rep := &Report{}
+	names := newOutputNames()
+
+	for p := range paths {
+		if ctx.Err() != nil {
+			break
+		}
+		records, err := c.T6FileToStruct(p)
+		if err == nil {
+			name := strings.TrimSuffix(filepath.Base(p), filepath.Ext(p)) + ".csv"
+			if !opts.Flat {
+				name = filepath.Join(opts.inputDir(p), name)
+			}
+			name = filepath.Join(opts.OutputDir, name)
+			if _, err = names.claim(p, "", name, false, false); err == nil {
+				err = c.StructToCsvFile(records, name)
+			}
+		}
+		rep.add(ctx, p, err)
+	}
+
+	// Check whether the Walk failed.
+	rep.walkDone(ctx, <-errc)
+
+	return rep
+}
+
+// walkFiles starts a goroutine to walk the directory tree at root and send the
+// path of each regular file ending in one of suffixes on the string channel.
+// It sends the result of the walk on the error channel.  If ctx is done,
+// walkFiles abandons its work.
+func walkFiles(ctx context.Context, root string, suffixes ...string) (<-chan string, <-chan error) {
+	paths := make(chan string)
+	errc := make(chan error, 1)
+	go func() { // HL
+		// Close the paths channel after Walk returns.
+		defer close(paths) // HL
+		// No select needed for this send, since errc is buffered.
+		errc <- filepath.Walk(root, func(path string, info os.FileInfo, err error) error { // HL
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			if !hasSuffix(info.Name(), suffixes) {
+				return nil
+			}
+
+			select {
+			case paths <- path: // HL
+			case <-ctx.Done(): // HL
+				return ctx.Err()
+			}
+			return nil
+		})
+	}()
+	return paths, errc
+}
+
+func hasSuffix(name string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(strings.ToLower(name), suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package t6converter
+
+import (
+	"context"
+	"fmt"
+	c "github.com/dan-lind/t6converter/converters"
+	"io"
+)
+
+// maxProblems limits the problems listed per file, a broken file would
+// otherwise list every record.
+const maxProblems = 20
+
+// Verify checks the t6 file at path, or every t6 file below path if it is a
+// directory, and writes the problems found to w. A file that can't be read is
+// listed and fails, the others are still checked. It returns whether all files
+// passed, the error is set if the walk or writing to w failed.
+func Verify(ctx context.Context, path string, w io.Writer) (bool, error) {
+	paths, errc := walkFiles(ctx, path, "t6")
+
+	passed := true
+	var writeErr error
+	for p := range paths {
+		if writeErr != nil {
+			continue
+		}
+
+		problems, err := c.VerifyT6File(p)
+		if err != nil {
+			passed = false
+			_, writeErr = fmt.Fprintf(w, "ERR  %v: %v\n", p, err)
+			continue
+		}
+		if len(problems) == 0 {
+			_, writeErr = fmt.Fprintf(w, "OK   %v\n", p)
+			continue
+		}
+
+		passed = false
+		writeErr = writeProblems(w, p, problems)
+	}
+
+	if err := <-errc; err != nil {
+		return false, err
+	}
+	return passed && writeErr == nil, writeErr
+}
+
+// writeProblems lists the first maxProblems problems of the file at path.
+func writeProblems(w io.Writer, path string, problems []string) error {
+	if _, err := fmt.Fprintf(w, "FAIL %v: %v problems\n", path, len(problems)); err != nil {
+		return err
+	}
+	for i, problem := range problems {
+		if i == maxProblems {
+			_, err := fmt.Fprintf(w, "     ... and %v more\n", len(problems)-maxProblems)
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "     %v\n", problem); err != nil {
+			return err
+		}
+	}
+	return nil
+}